@@ -0,0 +1,192 @@
+package webserver
+
+import (
+	"context"
+	"github.com/rs/zerolog"
+	"net/http"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+const truncatedPathMarker = "...(truncated)"
+
+// accessLogEntry holds the data of a single http logger line
+type accessLogEntry struct {
+	latency    time.Duration
+	clientIP   string
+	path       string
+	route      string
+	serverName string
+	method     string
+	statusCode int
+	bodySize   int
+	requestID  uint64
+	cookies    []string
+	trailers   []string
+
+	contentEncoding  string
+	compressionRatio float64
+
+	// logger overrides the http logger level (see SetAccessLogLevel), headers are only logged at debug level
+	logger  *zerolog.Logger
+	headers http.Header
+
+	clientAbort bool
+	writeErr    string
+	timedOut    bool
+
+	flushed chan struct{} // is only set for the flush marker of asyncAccessLog
+}
+
+func (e accessLogEntry) write(logger *zerolog.Logger) {
+	if e.logger != nil {
+		logger = e.logger
+	}
+	event := logger.Info()
+	if e.clientAbort || e.timedOut {
+		event = logger.Warn()
+	}
+
+	event.
+		Int64("latency", latencyMillis(e.latency)).
+		Str("clientIp", e.clientIP).
+		Str("path", e.path).
+		Str("method", e.method).
+		Int("statusCode", e.statusCode).
+		Int("bodySize", e.bodySize).
+		Uint64("requestID", e.requestID)
+
+	if e.route != "" {
+		event.Str("route", e.route)
+	}
+	if e.serverName != "" {
+		event.Str("serverName", e.serverName)
+	}
+	if len(e.cookies) > 0 {
+		event.Strs("cookies", e.cookies)
+	}
+	if e.contentEncoding != "" {
+		event.Str("contentEncoding", e.contentEncoding)
+	}
+	if e.compressionRatio > 0 {
+		event.Float64("compressionRatio", e.compressionRatio)
+	}
+	if len(e.trailers) > 0 {
+		event.Strs("trailers", e.trailers)
+	}
+	if e.clientAbort {
+		event.Bool("clientAbort", true).Str("writeError", e.writeErr)
+	}
+	if e.timedOut {
+		event.Bool("timedOut", true)
+	}
+
+	event.Msg("http request")
+
+	if e.headers != nil {
+		logger.Debug().
+			Uint64("requestID", e.requestID).
+			Interface("headers", e.headers).
+			Msg("http request headers")
+	}
+}
+
+// latencyMillis is the latency as logged by the access logs
+func latencyMillis(latency time.Duration) int64 {
+	return latency.Milliseconds()
+}
+
+// truncatePath cuts the path to the max length (keeping it valid UTF-8) and marks it as truncated
+func truncatePath(path string, max int) string {
+	if max <= 0 || len(path) <= max {
+		return path
+	}
+	path = path[:max]
+	for len(path) > 0 && !utf8.ValidString(path) {
+		path = path[:len(path)-1]
+	}
+	return path + truncatedPathMarker
+}
+
+// asyncAccessLog decouples requests from the log sink speed, the entries are written
+// by a background goroutine and dropped when the buffer is full
+type asyncAccessLog struct {
+	logger  *zerolog.Logger
+	entries chan accessLogEntry
+	dropped uint64
+}
+
+func newAsyncAccessLog(logger *zerolog.Logger, size int) *asyncAccessLog {
+	l := &asyncAccessLog{
+		logger:  logger,
+		entries: make(chan accessLogEntry, size),
+	}
+	go l.run()
+	return l
+}
+
+func (l *asyncAccessLog) run() {
+	for e := range l.entries {
+		if e.flushed != nil {
+			close(e.flushed)
+			continue
+		}
+		e.write(l.logger)
+	}
+}
+
+// write never blocks, the entry is dropped if the buffer is full
+func (l *asyncAccessLog) write(e accessLogEntry) {
+	select {
+	case l.entries <- e:
+	default:
+		atomic.AddUint64(&l.dropped, 1)
+	}
+}
+
+// flush waits until all the entries buffered before the call are written or the context is done,
+// the entries left in the buffer are dropped then
+func (l *asyncAccessLog) flush(ctx context.Context) {
+	if ctx.Err() != nil {
+		l.discard()
+		return
+	}
+	flushed := make(chan struct{})
+	select {
+	case l.entries <- accessLogEntry{flushed: flushed}:
+	case <-ctx.Done():
+		l.discard()
+		return
+	}
+	select {
+	case <-flushed:
+	case <-ctx.Done():
+		l.discard()
+	}
+}
+
+// discard drops (and counts) the buffered entries
+func (l *asyncAccessLog) discard() {
+	for {
+		select {
+		case e := <-l.entries:
+			if e.flushed == nil {
+				atomic.AddUint64(&l.dropped, 1)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// DroppedAccessLogs returns the number of access log entries dropped due to the async or NDJSON log buffer overflow
+func (w *WebServer) DroppedAccessLogs() uint64 {
+	switch {
+	case w.accessLog != nil:
+		return atomic.LoadUint64(&w.accessLog.dropped)
+	case w.ndjsonLog != nil:
+		return atomic.LoadUint64(&w.ndjsonLog.dropped)
+	}
+	return 0
+}
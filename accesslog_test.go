@@ -0,0 +1,188 @@
+package webserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingWriter blocks writes until it's released
+type blockingWriter struct {
+	sync.Mutex
+	release chan struct{}
+	buf     bytes.Buffer
+}
+
+func (b *blockingWriter) Write(p []byte) (int, error) {
+	<-b.release
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *blockingWriter) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.String()
+}
+
+func TestWebServer_AsyncAccessLog(t *testing.T) {
+	var logs bytes.Buffer
+	sink := &blockingWriter{release: make(chan struct{})}
+
+	webServer := newTestWebServer(t, WebServerConfig{LoggerHttp: newTestLogger(sink), AsyncLogBuffer: 2}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	//the first entry blocks the writer goroutine, two more fill the buffer and the rest are dropped
+	for i := 0; i < 10; i++ {
+		if rec := serve(webServer, httptest.NewRequest("GET", "/", nil)); rec.Body.String() != "HELLO" {
+			t.Fatalf("Wrong answer: %v", rec.Body.String())
+		}
+	}
+
+	if dropped := webServer.DroppedAccessLogs(); dropped < 7 {
+		t.Fatalf("Wrong number of dropped access logs: %v", dropped)
+	}
+
+	close(sink.release)
+	if err := webServer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	written := strings.Count(sink.String(), "http request")
+	if written == 0 || uint64(written)+webServer.DroppedAccessLogs() != 10 {
+		t.Fatalf("Wrong number of written access logs: %v, dropped: %v", written, webServer.DroppedAccessLogs())
+	}
+}
+
+func TestWebServer_AsyncAccessLogStalledSink(t *testing.T) {
+	for _, forced := range []bool{false, true} {
+		var logs bytes.Buffer
+		sink := &blockingWriter{release: make(chan struct{})}
+
+		webServer := newTestWebServer(t, WebServerConfig{LoggerHttp: newTestLogger(sink), AsyncLogBuffer: 4}, &logs)
+		webServer.ServiceRegister("", &PublicWebService{})
+		for i := 0; i < 3; i++ {
+			serve(webServer, httptest.NewRequest("GET", "/", nil))
+		}
+		if !waitFor(time.Second, func() bool { return len(webServer.accessLog.entries) == 2 }) {
+			t.Fatalf("First entry isn't taken by the writer")
+		}
+
+		start := time.Now()
+		if forced {
+			_ = webServer.Close()
+		} else {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+			_ = webServer.Shutdown(ctx)
+			cancel()
+		}
+		if elapsed := time.Since(start); elapsed > time.Millisecond*500 {
+			t.Fatalf("Stalled access log sink holds the shutdown (forced: %v): %v", forced, elapsed)
+		}
+		//the first entry is stuck in the sink, the buffered ones are dropped
+		if dropped := webServer.DroppedAccessLogs(); dropped != 2 {
+			t.Fatalf("Wrong number of dropped access logs (forced: %v): %v", forced, dropped)
+		}
+		close(sink.release)
+	}
+}
+
+func TestWebServer_MaxLoggedPathLength(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{MaxLoggedPathLength: 32}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/search/:kind", Method: "GET", Handler: func(c *gin.Context) { c.Status(200) }},
+		},
+	})
+
+	serve(webServer, httptest.NewRequest("GET", "/search/items?q="+strings.Repeat("x", 1000), nil))
+
+	var entry struct {
+		Path  string `json:"path"`
+		Route string `json:"route"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Can't decode access log %q: %v", logs.String(), err)
+	}
+	if entry.Path != "/search/items?q="+strings.Repeat("x", 16)+truncatedPathMarker {
+		t.Fatalf("Wrong truncated path: %v", entry.Path)
+	}
+	if entry.Route != "/search/:kind" {
+		t.Fatalf("Wrong route: %v", entry.Route)
+	}
+
+	if p := truncatePath("/путь", 4); p != "/п"+truncatedPathMarker {
+		t.Fatalf("Wrong truncation of multibyte path: %v", p)
+	}
+}
+
+func TestWebServer_NoLoggingPaths(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{NoLoggingPaths: []string{"/probe"}}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/probe", Method: "GET", Handler: func(c *gin.Context) { c.Status(200) }},
+			{Path: "/", Method: "GET", Handler: func(c *gin.Context) { c.Status(200) }},
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		serve(webServer, httptest.NewRequest("GET", "/probe", nil))
+	}
+	if logs.Len() != 0 {
+		t.Fatalf("Probe requests are logged: %v", logs.String())
+	}
+
+	serve(webServer, httptest.NewRequest("GET", "/", nil))
+	var entry struct {
+		RequestID uint64 `json:"requestID"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Can't decode access log %q: %v", logs.String(), err)
+	}
+	if entry.RequestID != 4 {
+		t.Fatalf("RequestID isn't incremented for the skipped requests: %v", entry.RequestID)
+	}
+}
+
+func benchmarkHttpLogger(b *testing.B, path string) {
+	webServer, err := NewWebServer(WebServerConfig{
+		Logger:         newTestLogger(ioutil.Discard),
+		LoggerHttp:     newTestLogger(ioutil.Discard),
+		NoLoggingPaths: []string{"/probe"},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: path, Method: "GET", Handler: func(c *gin.Context) { c.Status(200) }},
+		},
+	})
+	req := httptest.NewRequest("GET", path, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		serve(webServer, req)
+	}
+}
+
+func BenchmarkHttpLogger_Logged(b *testing.B) {
+	benchmarkHttpLogger(b, "/logged")
+}
+
+func BenchmarkHttpLogger_Skipped(b *testing.B) {
+	benchmarkHttpLogger(b, "/probe")
+}
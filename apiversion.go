@@ -0,0 +1,54 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// APIVersionConfig describes the payload schema version negotiation
+type APIVersionConfig struct {
+	// Header carries the requested version, Accept-Version by default
+	Header    string
+	Supported []string
+	// Default is the version used if the header is absent, the request is rejected with 400 if it's empty
+	Default string
+}
+
+// APIVersion returns a middleware requiring a supported version in the version header.
+// It responds 400 if the version is missing (and there is no default) and 406 if it isn't supported,
+// otherwise the negotiated version is stored in the context as "apiVersion"
+func APIVersion(config APIVersionConfig) gin.HandlerFunc {
+	header := config.Header
+	if header == "" {
+		header = "Accept-Version"
+	}
+	header = http.CanonicalHeaderKey(header)
+
+	supported := make(map[string]bool, len(config.Supported))
+	for _, v := range config.Supported {
+		supported[v] = true
+	}
+
+	return func(c *gin.Context) {
+		version := requestHeader(c, header)
+		if version == "" {
+			if config.Default == "" {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"error":     "missing " + header + " header",
+					"supported": config.Supported,
+				})
+				return
+			}
+			version = config.Default
+		}
+		if !supported[version] {
+			c.AbortWithStatusJSON(http.StatusNotAcceptable, gin.H{
+				"error":     "unsupported version " + version,
+				"supported": config.Supported,
+			})
+			return
+		}
+		c.Set("apiVersion", version)
+		c.Next()
+	}
+}
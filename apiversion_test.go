@@ -0,0 +1,51 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIVersion(t *testing.T) {
+	tests := []struct {
+		defaultVersion string
+		version        string
+		status         int
+		negotiated     string
+	}{
+		{"", "2", http.StatusOK, "2"},
+		{"", "3", http.StatusNotAcceptable, ""},
+		{"", "", http.StatusBadRequest, ""},
+		{"1", "", http.StatusOK, "1"},
+		{"1", "2", http.StatusOK, "2"},
+	}
+
+	for _, test := range tests {
+		var logs bytes.Buffer
+		webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+		webServer.ServiceRegister("/api", &testWebService{
+			routes: []WebRoute{
+				{Path: "/items", Method: "GET", Handler: func(c *gin.Context) {
+					c.String(http.StatusOK, c.GetString("apiVersion"))
+				}},
+			},
+			middlewares: []func(ctx *gin.Context){
+				APIVersion(APIVersionConfig{Supported: []string{"1", "2"}, Default: test.defaultVersion}),
+			},
+		})
+
+		req := httptest.NewRequest("GET", "/api/items", nil)
+		if test.version != "" {
+			req.Header.Set("Accept-Version", test.version)
+		}
+		rec := serve(webServer, req)
+		if rec.Code != test.status {
+			t.Fatalf("Wrong status for %+v: %v", test, rec.Code)
+		}
+		if test.status == http.StatusOK && rec.Body.String() != test.negotiated {
+			t.Fatalf("Wrong negotiated version for %+v: %v", test, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// bufferedBody is a request body that rewinds itself when it's read to the end,
+// so every next reader gets the full body again
+type bufferedBody struct {
+	*bytes.Reader
+	data []byte
+}
+
+func (b *bufferedBody) Read(p []byte) (n int, err error) {
+	n, err = b.Reader.Read(p)
+	if err == io.EOF {
+		b.Reader.Reset(b.data)
+	}
+	return
+}
+
+func (b *bufferedBody) Close() error {
+	return nil
+}
+
+// BufferBody returns a middleware reading the whole request body into memory (bounded by MaxRequestBodySize)
+// and replacing it with a re-readable buffer, so that several middlewares and the handler can read the body in turn.
+// A reader must read the body to the end to let the next one get it from the beginning
+func (w *WebServer) BufferBody() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if _, ok := c.Request.Body.(*bufferedBody); ok {
+			c.Next()
+			return
+		}
+
+		var reader io.Reader = c.Request.Body
+		if w.config.MaxRequestBodySize > 0 {
+			reader = io.LimitReader(reader, w.config.MaxRequestBodySize+1)
+		}
+		data, err := ioutil.ReadAll(reader)
+		_ = c.Request.Body.Close()
+		//the body is limited by bodyLimit as well, its reader fails right after the limit is reached
+		if limit := w.config.MaxRequestBodySize; limit > 0 &&
+			(int64(len(data)) > limit || err != nil && int64(len(data)) == limit) {
+			w.Block(c, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+		if err != nil {
+			w.Block(c, http.StatusBadRequest, "request body read error")
+			return
+		}
+
+		c.Request.Body = &bufferedBody{bytes.NewReader(data), data}
+		c.Next()
+	}
+}
+
+// BufferedBody returns the request body buffered by the BufferBody middleware
+func BufferedBody(c *gin.Context) ([]byte, bool) {
+	if b, ok := c.Request.Body.(*bufferedBody); ok {
+		return b.data, true
+	}
+	return nil, false
+}
+
+// bodyLimit rejects the requests declaring a body larger than MaxRequestBodySize before the body is sent
+// and limits the body reader for the requests of unknown length.
+// The server sends the interim "100 Continue" response on the first body read only,
+// so the clients expecting it get 417 without sending the body if the declared length exceeds the limit
+func (w *WebServer) bodyLimit() gin.HandlerFunc {
+	limit := w.config.MaxRequestBodySize
+	return func(c *gin.Context) {
+		if w.rejectDeclaredBody(c) {
+			return
+		}
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
+
+// rejectDeclaredBody responds 417 (to the clients expecting 100 Continue) or 413 if the declared request body
+// exceeds MaxRequestBodySize, it reports whether the request is rejected
+func (w *WebServer) rejectDeclaredBody(c *gin.Context) bool {
+	if limit := w.config.MaxRequestBodySize; limit <= 0 || c.Request.ContentLength <= limit {
+		return false
+	}
+	if strings.EqualFold(c.GetHeader("Expect"), "100-continue") {
+		w.Block(c, http.StatusExpectationFailed, "declared request body too large")
+	} else {
+		w.Block(c, http.StatusRequestEntityTooLarge, "declared request body too large")
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package webserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebServer_BufferBody(t *testing.T) {
+	var logs bytes.Buffer
+	var middlewareBody, handlerBody []byte
+	payload := strings.Repeat("payload", 100)
+
+	webServer := newTestWebServer(t, WebServerConfig{MaxRequestBodySize: 1024}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/upload", Method: "POST", Handler: func(c *gin.Context) {
+				handlerBody, _ = ioutil.ReadAll(c.Request.Body)
+				c.Status(http.StatusOK)
+			}},
+		},
+		middlewares: []func(ctx *gin.Context){
+			webServer.BufferBody(),
+			func(c *gin.Context) { middlewareBody, _ = ioutil.ReadAll(c.Request.Body) },
+		},
+	})
+
+	rec := serve(webServer, httptest.NewRequest("POST", "/upload", strings.NewReader(payload)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Wrong status code: %v", rec.Code)
+	}
+	if string(middlewareBody) != payload {
+		t.Fatalf("Middleware got wrong body: %v", string(middlewareBody))
+	}
+	if string(handlerBody) != payload {
+		t.Fatalf("Handler got wrong body: %v", string(handlerBody))
+	}
+
+	rec = serve(webServer, httptest.NewRequest("POST", "/upload", strings.NewReader(strings.Repeat(payload, 2))))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Wrong status code for too large body: %v", rec.Code)
+	}
+}
+
+func TestWebServer_ExpectContinue(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{Port: 9096, MaxRequestBodySize: 1024}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/upload", Method: "POST", Handler: func(c *gin.Context) {
+				body, _ := ioutil.ReadAll(c.Request.Body)
+				c.String(http.StatusOK, "%d", len(body))
+			}},
+		},
+	})
+	if err := webServer.RunBg(); err != nil {
+		t.Fatal(err)
+	}
+	defer webServer.Shutdown(context.Background())
+
+	send := func(contentLength int) (*bufio.Reader, net.Conn) {
+		conn, err := net.Dial("tcp", "localhost:9096")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = conn.SetDeadline(time.Now().Add(time.Second * 2))
+		fmt.Fprintf(conn, "POST /upload HTTP/1.1\r\nHost: localhost\r\nContent-Length: %d\r\nExpect: 100-continue\r\n\r\n", contentLength)
+		return bufio.NewReader(conn), conn
+	}
+
+	//declared body exceeds the limit, the final response comes without the body sent
+	reader, conn := send(4096)
+	status, err := reader.ReadString('\n')
+	conn.Close()
+	if err != nil || !strings.Contains(status, "417") {
+		t.Fatalf("Wrong response to oversized upload: %q, %v", status, err)
+	}
+
+	//declared body fits the limit, the body is sent after the interim response
+	reader, conn = send(10)
+	defer conn.Close()
+	status, err = reader.ReadString('\n')
+	if err != nil || !strings.Contains(status, "100 Continue") {
+		t.Fatalf("Wrong interim response: %q, %v", status, err)
+	}
+	if _, err = reader.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(conn, "0123456789")
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "10" {
+		t.Fatalf("Wrong final response: %v %q", resp.StatusCode, string(body))
+	}
+}
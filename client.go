@@ -0,0 +1,64 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// traceHeaders are the trace context headers propagated from the incoming request as is
+var traceHeaders = []string{"Traceparent", "Tracestate"}
+
+// correlationTransport injects the correlation headers into every outbound request
+type correlationTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+func (t *correlationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	//RoundTripper must not modify the original request
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		if req.Header.Get(name) == "" {
+			req.Header[name] = values
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// CorrelationHeaders returns the headers correlating outbound requests with the current one:
+// the incoming correlation ID (or requestID if there is none) and the trace context headers
+func CorrelationHeaders(c *gin.Context) http.Header {
+	headers := http.Header{}
+	name := "X-Request-Id"
+	if w, ok := c.Get("webServer"); ok {
+		name = w.(*WebServer).config.CorrelationHeader
+	}
+
+	if id := requestHeader(c, name); id != "" {
+		headers.Set(name, id)
+	} else {
+		headers.Set(name, strconv.FormatUint(RequestID(c), 10))
+	}
+	for _, h := range traceHeaders {
+		if v := requestHeader(c, h); v != "" {
+			headers.Set(h, v)
+		}
+	}
+	return headers
+}
+
+// RoundTripperFromContext wraps the base RoundTripper (http.DefaultTransport if nil)
+// to inject the current request's correlation headers into outbound requests
+func RoundTripperFromContext(c *gin.Context, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &correlationTransport{base, CorrelationHeaders(c)}
+}
+
+// ClientFromContext returns an http client injecting the current request's correlation headers
+// into outbound requests, so the calls to other services can be traced back to the request
+func ClientFromContext(c *gin.Context) *http.Client {
+	return &http.Client{Transport: RoundTripperFromContext(c, nil)}
+}
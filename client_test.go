@@ -0,0 +1,53 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientFromContext(t *testing.T) {
+	var logs bytes.Buffer
+	var outbound http.Header
+
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		outbound = r.Header.Clone()
+	}))
+	defer backend.Close()
+
+	webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/proxy", Method: "GET", Handler: func(c *gin.Context) {
+				resp, err := ClientFromContext(c).Get(backend.URL)
+				if err != nil {
+					c.Status(http.StatusBadGateway)
+					return
+				}
+				resp.Body.Close()
+				c.Status(http.StatusOK)
+			}},
+		},
+	})
+
+	rec := serve(webServer, httptest.NewRequest("GET", "/proxy", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Wrong status code: %v", rec.Code)
+	}
+	if id := outbound.Get("X-Request-ID"); id != "1" {
+		t.Fatalf("Wrong outbound correlation header: %q", id)
+	}
+
+	req := httptest.NewRequest("GET", "/proxy", nil)
+	req.Header.Set("X-Request-ID", "upstream-id")
+	req.Header.Set("Traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	serve(webServer, req)
+	if id := outbound.Get("X-Request-ID"); id != "upstream-id" {
+		t.Fatalf("Incoming correlation ID wasn't propagated: %q", id)
+	}
+	if outbound.Get("Traceparent") != req.Header.Get("Traceparent") {
+		t.Fatalf("Trace context wasn't propagated")
+	}
+}
@@ -0,0 +1,137 @@
+package webserver
+
+import (
+	"compress/gzip"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// incompressibleTypes are the content type prefixes of the already compressed formats
+var incompressibleTypes = []string{"image/", "video/", "audio/", "font/woff", "application/zip", "application/gzip",
+	"application/x-gzip", "application/x-bzip2", "application/x-7z-compressed", "application/x-rar-compressed", "application/zstd"}
+
+// isCompressed reports whether the content type is an already compressed format
+func isCompressed(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	if strings.HasPrefix(contentType, "image/svg+xml") {
+		return false
+	}
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipWriter compresses the response body, it decides on the first write: the responses already encoded
+// by the handler, the ones without a body (204, 304), the partial ones (206, Content-Range), the already compressed
+// content types and the ones marked "noCompression" in the context are sent as is
+type gzipWriter struct {
+	gin.ResponseWriter
+	c        *gin.Context
+	gz       *gzip.Writer
+	identity bool
+	original int
+}
+
+// start decides on the encoding before anything reaches the underlying writer,
+// the headers sent already (e.g. by WriteHeaderNow) make the body go as is
+func (gw *gzipWriter) start() {
+	if gw.gz != nil || gw.identity {
+		return
+	}
+	header := gw.Header()
+	status := gw.Status()
+	if gw.ResponseWriter.Written() || header.Get("Content-Encoding") != "" || status == http.StatusNoContent || status == http.StatusNotModified ||
+		status == http.StatusPartialContent || header.Get("Content-Range") != "" ||
+		isCompressed(header.Get("Content-Type")) || gw.c.GetBool("noCompression") {
+		gw.identity = true
+		return
+	}
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+	gw.gz = gzip.NewWriter(gw.ResponseWriter)
+}
+
+func (gw *gzipWriter) Write(data []byte) (int, error) {
+	gw.start()
+	if gw.identity {
+		return gw.ResponseWriter.Write(data)
+	}
+	gw.original += len(data)
+	return gw.gz.Write(data)
+}
+
+func (gw *gzipWriter) WriteString(s string) (int, error) {
+	return gw.Write([]byte(s))
+}
+
+// WriteHeaderNow sends the headers without a body known, such a response isn't compressed
+func (gw *gzipWriter) WriteHeaderNow() {
+	if gw.gz == nil {
+		gw.identity = true
+	}
+	gw.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush decides on the encoding if nothing is written yet, so the streaming responses flushed
+// before the first write (e.g. text/event-stream) are sent with the matching Content-Encoding
+func (gw *gzipWriter) Flush() {
+	gw.start()
+	if gw.gz != nil {
+		_ = gw.gz.Flush()
+	}
+	gw.ResponseWriter.Flush()
+}
+
+// acceptsGzip reports whether the client accepts the gzip encoded response
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(value, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		rejected := false
+		for _, param := range params[1:] {
+			if param = strings.TrimSpace(param); strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				rejected = err == nil && q == 0
+			}
+		}
+		if !rejected {
+			return true
+		}
+	}
+	return false
+}
+
+// compression gzips the responses for the clients accepting it. The applied encoding and the uncompressed size
+// are stored in the context as "contentEncoding" and "uncompressedSize" for the access log.
+// All the responses vary by Accept-Encoding, so the caches don't serve the identity ones to the gzip clients
+func (w *WebServer) compression() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(c.Request) {
+			c.Next()
+			return
+		}
+
+		writer := &gzipWriter{ResponseWriter: c.Writer, c: c}
+		c.Writer = writer
+		defer func() {
+			c.Writer = writer.ResponseWriter
+		}()
+
+		c.Next()
+
+		if writer.gz != nil {
+			_ = writer.gz.Close()
+			c.Set("contentEncoding", "gzip")
+			c.Set("uncompressedSize", writer.original)
+		}
+	}
+}
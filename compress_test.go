@@ -0,0 +1,144 @@
+package webserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebServer_Compression(t *testing.T) {
+	var logs bytes.Buffer
+
+	body := strings.Repeat("compressible ", 100)
+	webServer := newTestWebServer(t, WebServerConfig{Compression: true}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/test", Method: "GET", Handler: func(c *gin.Context) {
+				c.String(http.StatusOK, body)
+			}},
+		},
+	})
+
+	var entry struct {
+		ContentEncoding  string  `json:"contentEncoding"`
+		CompressionRatio float64 `json:"compressionRatio"`
+	}
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "br;q=1.0, gzip;q=0.8")
+	w := serve(webServer, req)
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Response isn't compressed")
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, _ := ioutil.ReadAll(gz); string(decoded) != body {
+		t.Fatalf("Wrong decompressed body: %q", decoded)
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Can't decode access log %q: %v", logs.String(), err)
+	}
+	if entry.ContentEncoding != "gzip" || entry.CompressionRatio <= 1 {
+		t.Fatalf("Wrong compression is logged: %+v", entry)
+	}
+
+	logs.Reset()
+	entry.ContentEncoding, entry.CompressionRatio = "", 0
+	req = httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0")
+	w = serve(webServer, req)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != body {
+		t.Fatalf("Response is compressed for the client rejected gzip")
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Can't decode access log %q: %v", logs.String(), err)
+	}
+	if entry.ContentEncoding != "identity" || entry.CompressionRatio != 0 {
+		t.Fatalf("Wrong compression is logged: %+v", entry)
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("Identity response doesn't vary by Accept-Encoding: %v", w.Header())
+	}
+}
+
+func TestWebServer_CompressionSkipped(t *testing.T) {
+	var logs bytes.Buffer
+
+	body := strings.Repeat("compressible ", 100)
+	webServer := newTestWebServer(t, WebServerConfig{Compression: true}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/file.txt", Method: "GET", Handler: func(c *gin.Context) {
+				http.ServeContent(c.Writer, c.Request, "file.txt", time.Time{}, strings.NewReader(body))
+			}},
+			{Path: "/image", Method: "GET", Handler: func(c *gin.Context) {
+				c.Data(http.StatusOK, "image/png", []byte(body))
+			}},
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/file.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-11")
+	w := serve(webServer, req)
+	if w.Code != http.StatusPartialContent || w.Header().Get("Content-Encoding") != "" || w.Body.String() != body[:12] {
+		t.Fatalf("Partial response is compressed: %v %v %q", w.Code, w.Header(), w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/image", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = serve(webServer, req)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != body || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("Already compressed content type is compressed: %v", w.Header())
+	}
+}
+
+func TestWebServer_CompressionFlushBeforeWrite(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{Compression: true}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/events", Method: "GET", Handler: func(c *gin.Context) {
+				c.Header("Content-Type", "text/event-stream")
+				c.Writer.Flush()
+				_, _ = c.Writer.Write([]byte("data: event\n\n"))
+			}},
+			{Path: "/sent", Method: "GET", Handler: func(c *gin.Context) {
+				c.Writer.WriteHeaderNow()
+				_, _ = c.Writer.Write([]byte("sent as is"))
+			}},
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res := serve(webServer, req).Result()
+	if res.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Flushed response has no Content-Encoding: %v", res.Header)
+	}
+	gz, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatalf("Flushed response isn't gzipped: %v", err)
+	}
+	if decoded, _ := ioutil.ReadAll(gz); string(decoded) != "data: event\n\n" {
+		t.Fatalf("Wrong decompressed body: %q", decoded)
+	}
+
+	req = httptest.NewRequest("GET", "/sent", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res = serve(webServer, req).Result()
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.Header.Get("Content-Encoding") != "" || string(body) != "sent as is" {
+		t.Fatalf("Response with the headers sent before the write is compressed: %v %q", res.Header, body)
+	}
+}
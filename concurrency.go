@@ -0,0 +1,70 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// concurrencyLimiter allows MaxConcurrentRequests requests to be processed at once, the others wait in the queue
+// up to ConcurrencyQueueTimeout (0 means no waiting) and are answered 503 with Retry-After if no slot is freed.
+// Health requests are not limited
+func (w *WebServer) concurrencyLimiter() gin.HandlerFunc {
+	slots := make(chan struct{}, w.config.MaxConcurrentRequests)
+	timeout := w.config.ConcurrencyQueueTimeout
+	retryAfter := strconv.Itoa(int(math.Max(1, math.Ceil(timeout.Seconds()))))
+
+	return func(c *gin.Context) {
+		if w.isHealthPath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			if !w.waitSlot(c, slots, timeout) {
+				if c.Request.Context().Err() != nil {
+					//the client has gone
+					c.Abort()
+					return
+				}
+				c.Header("Retry-After", retryAfter)
+				c.AbortWithStatus(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		defer func() {
+			<-slots
+		}()
+		c.Next()
+	}
+}
+
+// waitSlot waits in the queue for a free slot, it returns false on the timeout or the request cancellation
+func (w *WebServer) waitSlot(c *gin.Context, slots chan struct{}, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return false
+	}
+	atomic.AddInt64(&w.queued, 1)
+	defer atomic.AddInt64(&w.queued, -1)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-c.Request.Context().Done():
+		return false
+	}
+}
+
+// QueuedRequests returns the number of the requests waiting for a slot of the concurrency limiter
+func (w *WebServer) QueuedRequests() int64 {
+	return atomic.LoadInt64(&w.queued)
+}
@@ -0,0 +1,65 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebServer_ConcurrencyQueueTimeout(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+
+	webServer := newTestWebServer(t, WebServerConfig{
+		MaxConcurrentRequests:   1,
+		ConcurrencyQueueTimeout: time.Millisecond * 200,
+	}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{{Path: "/slow", Method: "GET", Handler: func(c *gin.Context) {
+			started <- struct{}{}
+			<-release
+			c.Status(http.StatusOK)
+		}}},
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serve(webServer, httptest.NewRequest("GET", "/slow", nil))
+	}()
+	<-started
+
+	const queued = 3
+	codes := make(chan *httptest.ResponseRecorder, queued)
+	for i := 0; i < queued; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- serve(webServer, httptest.NewRequest("GET", "/slow", nil))
+		}()
+	}
+	if !waitFor(time.Second, func() bool { return webServer.QueuedRequests() == queued }) {
+		t.Fatalf("Wrong queue depth: %v", webServer.QueuedRequests())
+	}
+
+	for i := 0; i < queued; i++ {
+		rec := <-codes
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+			t.Fatalf("Queued request isn't timed out: %v %q", rec.Code, rec.Header().Get("Retry-After"))
+		}
+	}
+	if n := webServer.QueuedRequests(); n != 0 {
+		t.Fatalf("Queue isn't drained: %v", n)
+	}
+
+	close(release)
+	wg.Wait()
+	if rec := serve(webServer, httptest.NewRequest("GET", "/slow", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("Slot isn't freed: %v", rec.Code)
+	}
+}
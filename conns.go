@@ -0,0 +1,23 @@
+package webserver
+
+import (
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+// connState tracks the number of open connections, a connection is counted from its acceptance
+// until it's closed or hijacked regardless of the keep-alive transitions between active and idle states
+func (w *WebServer) connState(conn net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		atomic.AddInt64(&w.conns, 1)
+	case http.StateClosed, http.StateHijacked:
+		atomic.AddInt64(&w.conns, -1)
+	}
+}
+
+// ActiveConnections returns the number of currently open connections of a server started with RunBg
+func (w *WebServer) ActiveConnections() int {
+	return int(atomic.LoadInt64(&w.conns))
+}
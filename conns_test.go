@@ -0,0 +1,66 @@
+package webserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// waitFor polls the condition until it's met or the timeout happened
+func waitFor(timeout time.Duration, condition func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return condition()
+}
+
+func TestWebServer_ActiveConnections(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{Port: 9095}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	if err := webServer.RunBg(); err != nil {
+		t.Fatal(err)
+	}
+	defer webServer.Shutdown(context.Background())
+
+	if n := webServer.ActiveConnections(); n != 0 {
+		t.Fatalf("Wrong number of connections before connect: %v", n)
+	}
+
+	conn, err := net.Dial("tcp", "localhost:9095")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor(time.Second, func() bool { return webServer.ActiveConnections() == 1 }) {
+		t.Fatalf("Wrong number of connections after connect: %v", webServer.ActiveConnections())
+	}
+
+	//keep-alive request moves the connection to active and back to idle state, it must be still counted
+	req, _ := http.NewRequest("GET", "http://localhost:9095/", nil)
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := webServer.ActiveConnections(); n != 1 {
+		t.Fatalf("Wrong number of connections after keep-alive request: %v", n)
+	}
+
+	conn.Close()
+	if !waitFor(time.Second, func() bool { return webServer.ActiveConnections() == 0 }) {
+		t.Fatalf("Wrong number of connections after close: %v", webServer.ActiveConnections())
+	}
+}
@@ -0,0 +1,58 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxRanges is the number of the ranges served in one multipart/byteranges response,
+// the requests with more ranges are served in full
+const maxRanges = 16
+
+// ServeContent serves the content with http.ServeContent: the conditional requests, single ranges (206),
+// multiple ranges (206 multipart/byteranges) and the unsatisfiable ranges (416 with Content-Range).
+// The malformed Range headers, the units other than bytes and too many ranges are ignored, the full content is served (200).
+// The response isn't compressed, so the ranges match the content bytes
+func ServeContent(c *gin.Context, name string, modtime time.Time, content io.ReadSeeker) {
+	if rangeHeader := c.Request.Header.Get("Range"); rangeHeader != "" && !validRange(rangeHeader) {
+		c.Request.Header.Del("Range")
+	}
+	c.Set("noCompression", true)
+	http.ServeContent(c.Writer, c.Request, name, modtime, content)
+}
+
+// validRange checks the Range header syntax: "bytes=" followed by up to maxRanges "first-last", "first-" or "-suffix" specs
+func validRange(header string) bool {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return false
+	}
+	specs := strings.Split(header[len(prefix):], ",")
+	if len(specs) > maxRanges {
+		return false
+	}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		i := strings.IndexByte(spec, '-')
+		if i < 0 {
+			return false
+		}
+		first, last := spec[:i], spec[i+1:]
+		if first == "" && last == "" || !isDigits(first) || !isDigits(last) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
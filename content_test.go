@@ -0,0 +1,80 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeContent(t *testing.T) {
+	content := "0123456789abcdefghij"
+	webServer := newTestWebServer(t, WebServerConfig{Compression: true}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{{Path: "/media", Method: "GET", Handler: func(c *gin.Context) {
+			ServeContent(c, "media.txt", time.Time{}, strings.NewReader(content))
+		}}},
+	})
+
+	get := func(rangeHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/media", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		return serve(webServer, req)
+	}
+
+	t.Run("single", func(t *testing.T) {
+		w := get("bytes=2-5")
+		if w.Code != http.StatusPartialContent || w.Body.String() != "2345" || w.Header().Get("Content-Range") != "bytes 2-5/20" {
+			t.Fatalf("Wrong single range response: %v %q %v", w.Code, w.Body.String(), w.Header())
+		}
+		if w.Header().Get("Content-Encoding") != "" {
+			t.Fatalf("Range response is compressed")
+		}
+	})
+
+	t.Run("multi", func(t *testing.T) {
+		w := get("bytes=0-1, -3")
+		mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		if w.Code != http.StatusPartialContent || err != nil || mediaType != "multipart/byteranges" {
+			t.Fatalf("Wrong multi range response: %v %v", w.Code, w.Header().Get("Content-Type"))
+		}
+		reader := multipart.NewReader(w.Body, params["boundary"])
+		var parts []string
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			data, _ := ioutil.ReadAll(part)
+			parts = append(parts, part.Header.Get("Content-Range")+" "+string(data))
+		}
+		if len(parts) != 2 || parts[0] != "bytes 0-1/20 01" || parts[1] != "bytes 17-19/20 hij" {
+			t.Fatalf("Wrong parts: %q", parts)
+		}
+	})
+
+	t.Run("unsatisfiable", func(t *testing.T) {
+		w := get("bytes=30-40")
+		if w.Code != http.StatusRequestedRangeNotSatisfiable || w.Header().Get("Content-Range") != "bytes */20" {
+			t.Fatalf("Wrong unsatisfiable range response: %v %v", w.Code, w.Header())
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, rangeHeader := range []string{"bytes=a-b", "items=0-1", "bytes=-", "bytes=" + strings.Repeat("0-0,", maxRanges) + "0-0"} {
+			w := get(rangeHeader)
+			if w.Code != http.StatusOK || w.Body.String() != content {
+				t.Fatalf("%q: full content isn't served: %v %q", rangeHeader, w.Code, w.Body.String())
+			}
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package webserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCookieTampered = errors.New("signed cookie is tampered")
+	ErrCookieExpired  = errors.New("signed cookie is expired")
+	ErrNoCookieSecret = errors.New("cookie secret isn't configured")
+)
+
+// signCookie encodes the value with its expiration time and the HMAC signature
+// covering the cookie name, value and expiration
+func (w *WebServer) signCookie(name, value string, expires time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value)) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + w.cookieSignature(name, payload)
+}
+
+func (w *WebServer) cookieSignature(name, payload string) string {
+	mac := hmac.New(sha256.New, w.config.CookieSecret)
+	mac.Write([]byte(name + "=" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SetSignedCookie sets the cookie with the value signed by CookieSecret,
+// the value is readable by GetSignedCookie until maxAge expires
+func (w *WebServer) SetSignedCookie(c *gin.Context, name, value string, maxAge time.Duration, path, domain string, secure, httpOnly bool) error {
+	if len(w.config.CookieSecret) == 0 {
+		return ErrNoCookieSecret
+	}
+	signed := w.signCookie(name, value, time.Now().Add(maxAge))
+	c.SetCookie(name, signed, int(maxAge.Seconds()), path, domain, secure, httpOnly)
+	return nil
+}
+
+// GetSignedCookie returns the value of the cookie set by SetSignedCookie verifying its signature and expiration.
+// The value is also stored in the context as "signedCookie.<name>"
+func (w *WebServer) GetSignedCookie(c *gin.Context, name string) (string, error) {
+	if len(w.config.CookieSecret) == 0 {
+		return "", ErrNoCookieSecret
+	}
+	signed, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+
+	i := strings.LastIndexByte(signed, '.')
+	if i < 0 {
+		return "", ErrCookieTampered
+	}
+	payload, signature := signed[:i], signed[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(w.cookieSignature(name, payload))) {
+		return "", ErrCookieTampered
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 2 {
+		return "", ErrCookieTampered
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", ErrCookieTampered
+	}
+	if time.Now().Unix() > expires {
+		return "", ErrCookieExpired
+	}
+	value, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", ErrCookieTampered
+	}
+
+	c.Set("signedCookie."+name, string(value))
+	return string(value), nil
+}
+
+// SignedCookies returns a middleware decoding the signed cookies into the context (see GetSignedCookie),
+// missing, tampered and expired cookies are skipped
+func (w *WebServer) SignedCookies(names ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, name := range names {
+			_, _ = w.GetSignedCookie(c, name)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebServer_SignedCookie(t *testing.T) {
+	var logs bytes.Buffer
+	var value string
+	var readErr error
+
+	webServer := newTestWebServer(t, WebServerConfig{CookieSecret: []byte("test secret")}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/login", Method: "GET", Handler: func(c *gin.Context) {
+				readErr = webServer.SetSignedCookie(c, "session", "user42", time.Hour, "/", "", false, true)
+			}},
+			{Path: "/profile", Method: "GET", Handler: func(c *gin.Context) {
+				value, readErr = webServer.GetSignedCookie(c, "session")
+				if readErr == nil && c.GetString("signedCookie.session") != value {
+					t.Errorf("Cookie value isn't stored in the context")
+				}
+			}},
+		},
+	})
+
+	rec := serve(webServer, httptest.NewRequest("GET", "/login", nil))
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Wrong cookies: %v", cookies)
+	}
+
+	//valid
+	req := httptest.NewRequest("GET", "/profile", nil)
+	req.AddCookie(cookies[0])
+	serve(webServer, req)
+	if readErr != nil || value != "user42" {
+		t.Fatalf("Wrong valid cookie value: %q, %v", value, readErr)
+	}
+
+	//tampered
+	tampered := webServer.signCookie("session", "user42", time.Now().Add(time.Hour))
+	tampered = tampered[:len(tampered)-2] + "xx"
+	req = httptest.NewRequest("GET", "/profile", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: tampered})
+	serve(webServer, req)
+	if readErr != ErrCookieTampered {
+		t.Fatalf("Tampered cookie isn't detected: %v", readErr)
+	}
+
+	//signed for another cookie name
+	req = httptest.NewRequest("GET", "/profile", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: webServer.signCookie("admin", "user42", time.Now().Add(time.Hour))})
+	serve(webServer, req)
+	if readErr != ErrCookieTampered {
+		t.Fatalf("Cookie of another name isn't detected: %v", readErr)
+	}
+
+	//expired
+	req = httptest.NewRequest("GET", "/profile", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: webServer.signCookie("session", "user42", time.Now().Add(-time.Minute))})
+	serve(webServer, req)
+	if readErr != ErrCookieExpired {
+		t.Fatalf("Expired cookie isn't detected: %v", readErr)
+	}
+}
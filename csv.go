@@ -0,0 +1,52 @@
+package webserver
+
+import (
+	"encoding/csv"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// StreamCSV streams the rows as text/csv until the channel is closed, the header row is written first if not empty.
+// The output is flushed to the client whenever no more rows are ready. It returns on the client disconnect
+// (the context error is returned, the producer should stop on the same context) or the write error
+func StreamCSV(c *gin.Context, header []string, rows <-chan []string) error {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	flush := func() error {
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	if len(header) > 0 {
+		if err := w.Write(header); err != nil {
+			return err
+		}
+	}
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case row, ok := <-rows:
+			if !ok {
+				return flush()
+			}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
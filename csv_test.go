@@ -0,0 +1,65 @@
+package webserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestStreamCSV(t *testing.T) {
+	data := [][]string{
+		{"1", "plain"},
+		{"2", "with, comma"},
+		{"3", "with \"quotes\"\nand newline"},
+	}
+
+	webServer := newTestWebServer(t, WebServerConfig{}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{{Path: "/report.csv", Method: "GET", Handler: func(c *gin.Context) {
+			rows := make(chan []string)
+			go func() {
+				defer close(rows)
+				for _, row := range data {
+					rows <- row
+				}
+			}()
+			if err := StreamCSV(c, []string{"id", "name"}, rows); err != nil {
+				t.Errorf("Stream error: %v", err)
+			}
+		}}},
+	})
+
+	w := serve(webServer, httptest.NewRequest("GET", "/report.csv", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv; charset=utf-8" || !w.Flushed {
+		t.Fatalf("Wrong response: %v %v flushed %v", w.Code, w.Header().Get("Content-Type"), w.Flushed)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Can't decode the streamed CSV: %v", err)
+	}
+	if expected := append([][]string{{"id", "name"}}, data...); !reflect.DeepEqual(records, expected) {
+		t.Fatalf("Wrong CSV records: %q", records)
+	}
+}
+
+func TestStreamCSV_ClientGone(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{}, &bytes.Buffer{})
+	streamErr := make(chan error, 1)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{{Path: "/report.csv", Method: "GET", Handler: func(c *gin.Context) {
+			streamErr <- StreamCSV(c, nil, make(chan []string))
+		}}},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	serve(webServer, httptest.NewRequest("GET", "/report.csv", nil).WithContext(ctx))
+	if err := <-streamErr; err != context.Canceled {
+		t.Fatalf("Stream isn't stopped on the client disconnect: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package webserver
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// deadlineListener wraps the accepted connections with deadlineConn if the connection deadlines are configured
+func (w *WebServer) deadlineListener(ln net.Listener) net.Listener {
+	if w.config.ConnReadDeadline <= 0 && w.config.ConnWriteDeadline <= 0 {
+		return ln
+	}
+	return &deadlineListener{Listener: ln, read: w.config.ConnReadDeadline, write: w.config.ConnWriteDeadline}
+}
+
+type deadlineListener struct {
+	net.Listener
+	read  time.Duration
+	write time.Duration
+}
+
+func (l *deadlineListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &deadlineConn{Conn: conn, read: l.read, write: l.write}, nil
+}
+
+// deadlineConn sets the absolute read/write deadlines of the connection refreshing them when a new request starts,
+// that's detected as the first read after the response was written. The deadlines set by net/http itself
+// (http.Server timeouts, aborting the background reads) are honored, the earliest deadline wins.
+// The keep-alive idle wait for the next request starts with a read, so an idle connection is closed
+// once the read deadline expired too
+type deadlineConn struct {
+	net.Conn
+	read  time.Duration
+	write time.Duration
+
+	mu            sync.Mutex
+	started       bool
+	wrote         bool
+	readDeadline  time.Time
+	writeDeadline time.Time
+	httpRead      time.Time
+	httpWrite     time.Time
+}
+
+func (dc *deadlineConn) Read(b []byte) (int, error) {
+	dc.mu.Lock()
+	if !dc.started || dc.wrote {
+		dc.started, dc.wrote = true, false
+		now := time.Now()
+		if dc.read > 0 {
+			dc.readDeadline = now.Add(dc.read)
+			_ = dc.Conn.SetReadDeadline(earliestDeadline(dc.httpRead, dc.readDeadline))
+		}
+		if dc.write > 0 {
+			dc.writeDeadline = now.Add(dc.write)
+			_ = dc.Conn.SetWriteDeadline(earliestDeadline(dc.httpWrite, dc.writeDeadline))
+		}
+	}
+	dc.mu.Unlock()
+	return dc.Conn.Read(b)
+}
+
+func (dc *deadlineConn) Write(b []byte) (int, error) {
+	dc.mu.Lock()
+	dc.wrote = true
+	dc.mu.Unlock()
+	return dc.Conn.Write(b)
+}
+
+func (dc *deadlineConn) SetDeadline(t time.Time) error {
+	if err := dc.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return dc.SetWriteDeadline(t)
+}
+
+func (dc *deadlineConn) SetReadDeadline(t time.Time) error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.httpRead = t
+	return dc.Conn.SetReadDeadline(earliestDeadline(t, dc.readDeadline))
+}
+
+func (dc *deadlineConn) SetWriteDeadline(t time.Time) error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.httpWrite = t
+	return dc.Conn.SetWriteDeadline(earliestDeadline(t, dc.writeDeadline))
+}
+
+// earliestDeadline returns the earliest of the deadlines, zero means no deadline
+func earliestDeadline(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,48 @@
+package webserver
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWebServer_ConnDeadline(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{Port: 9098, ConnReadDeadline: time.Millisecond * 300}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	if err := webServer.RunBg(); err != nil {
+		t.Fatal(err)
+	}
+	defer webServer.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", "localhost:9098")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	//the request is never completed
+	if _, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_ = conn.SetReadDeadline(start.Add(time.Second * 5))
+	_, _ = ioutil.ReadAll(conn)
+	if elapsed := time.Since(start); elapsed > time.Second*2 {
+		t.Fatalf("Stalled connection isn't closed after the deadline: %v", elapsed)
+	}
+}
+
+func TestEarliestDeadline(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Second)
+	if earliestDeadline(time.Time{}, now) != now || earliestDeadline(now, time.Time{}) != now ||
+		earliestDeadline(later, now) != now || earliestDeadline(now, later) != now ||
+		!earliestDeadline(time.Time{}, time.Time{}).IsZero() {
+		t.Fatalf("Wrong earliest deadline")
+	}
+}
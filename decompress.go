@@ -0,0 +1,70 @@
+package webserver
+
+import (
+	"compress/gzip"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// UnknownEncodingPolicy defines how the request decompression treats the unsupported Content-Encoding
+type UnknownEncodingPolicy int
+
+const (
+	// UnknownEncodingReject responds 415 with the supported encodings in Accept-Encoding
+	UnknownEncodingReject UnknownEncodingPolicy = iota
+	// UnknownEncodingPass passes the request body to the handler undecoded
+	UnknownEncodingPass
+)
+
+type gzipRequestBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipRequestBody) Close() error {
+	_ = b.Reader.Close()
+	return b.body.Close()
+}
+
+// requestDecompression decodes the gzip encoded request bodies. The declared (encoded) length is checked
+// against MaxRequestBodySize before the body is read, bodyLimit runs after it and limits the decoded body size
+func (w *WebServer) requestDecompression() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+		switch encoding {
+		case "", "identity":
+			c.Next()
+			return
+		case "gzip", "x-gzip":
+		default:
+			if w.config.UnknownRequestEncoding == UnknownEncodingPass {
+				c.Next()
+				return
+			}
+			c.Header("Accept-Encoding", "gzip")
+			c.AbortWithStatus(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		//gzip reader reads the header right away triggering 100 Continue, the oversized bodies are rejected before
+		if w.rejectDeclaredBody(c) {
+			return
+		}
+		gz, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		c.Request.Body = &gzipRequestBody{Reader: gz, body: c.Request.Body}
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Request.ContentLength = -1
+		c.Next()
+	}
+}
@@ -0,0 +1,120 @@
+package webserver
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newDecompressTestWebServer(t *testing.T, policy UnknownEncodingPolicy) *WebServer {
+	webServer := newTestWebServer(t, WebServerConfig{DecompressRequests: true, UnknownRequestEncoding: policy}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{{Path: "/echo", Method: "POST", Handler: func(c *gin.Context) {
+			body, err := ioutil.ReadAll(c.Request.Body)
+			if err != nil {
+				c.Status(http.StatusBadRequest)
+				return
+			}
+			c.Data(http.StatusOK, "text/plain", body)
+		}}},
+	})
+	return webServer
+}
+
+func TestWebServer_RequestDecompression(t *testing.T) {
+	webServer := newDecompressTestWebServer(t, UnknownEncodingReject)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write([]byte("payload"))
+	_ = gz.Close()
+
+	req := httptest.NewRequest("POST", "/echo", &compressed)
+	req.Header.Set("Content-Encoding", "gzip")
+	if w := serve(webServer, req); w.Code != http.StatusOK || w.Body.String() != "payload" {
+		t.Fatalf("Wrong decompressed request: %v %q", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/echo", bytes.NewBufferString("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	if w := serve(webServer, req); w.Code != http.StatusBadRequest {
+		t.Fatalf("Wrong status of the broken gzip body: %v", w.Code)
+	}
+}
+
+func TestWebServer_UnknownRequestEncoding(t *testing.T) {
+	tests := []struct {
+		policy UnknownEncodingPolicy
+		code   int
+		body   string
+	}{
+		{UnknownEncodingReject, http.StatusUnsupportedMediaType, ""},
+		{UnknownEncodingPass, http.StatusOK, "brotli bytes"},
+	}
+	for _, test := range tests {
+		webServer := newDecompressTestWebServer(t, test.policy)
+		req := httptest.NewRequest("POST", "/echo", bytes.NewBufferString("brotli bytes"))
+		req.Header.Set("Content-Encoding", "br")
+		w := serve(webServer, req)
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Fatalf("Policy %v: wrong response: %v %q", test.policy, w.Code, w.Body.String())
+		}
+		if test.policy == UnknownEncodingReject && w.Header().Get("Accept-Encoding") != "gzip" {
+			t.Fatalf("Supported encodings aren't reported")
+		}
+	}
+}
+
+func TestWebServer_RequestDecompressionBodyLimit(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{Port: 9102, DecompressRequests: true, MaxRequestBodySize: 1024}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{{Path: "/echo", Method: "POST", Handler: func(c *gin.Context) {
+			body, err := ioutil.ReadAll(c.Request.Body)
+			if err != nil {
+				c.Status(http.StatusRequestEntityTooLarge)
+				return
+			}
+			c.String(http.StatusOK, "%d", len(body))
+		}}},
+	})
+
+	//the decoded body is limited
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write(make([]byte, 4096))
+	_ = gz.Close()
+	req := httptest.NewRequest("POST", "/echo", &compressed)
+	req.Header.Set("Content-Encoding", "gzip")
+	if w := serve(webServer, req); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Decoded body over the limit is accepted: %v %q", w.Code, w.Body.String())
+	}
+
+	if err := webServer.RunBg(); err != nil {
+		t.Fatal(err)
+	}
+	defer webServer.Shutdown(context.Background())
+
+	//the declared length is rejected without the body sent
+	conn, err := net.Dial("tcp", "localhost:9102")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(time.Second * 2))
+	fmt.Fprintf(conn, "POST /echo HTTP/1.1\r\nHost: localhost\r\nContent-Encoding: gzip\r\n"+
+		"Content-Length: %d\r\nExpect: 100-continue\r\n\r\n", 1<<30)
+	status, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || !strings.Contains(status, "417") {
+		t.Fatalf("Wrong response to oversized gzip upload: %q, %v", status, err)
+	}
+}
@@ -0,0 +1,49 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// deprecationLogInterval limits the deprecated route usage logging to one line per route per interval
+const deprecationLogInterval = time.Minute
+
+// deprecatedHandler adds the Deprecation, Warning and Sunset (if set) headers to the route responses
+// and logs the route usage at most once per deprecationLogInterval
+func (w *WebServer) deprecatedHandler(handler gin.HandlerFunc, route WebRoute) gin.HandlerFunc {
+	warning := `299 - "Deprecated API"`
+	sunset := ""
+	if !route.Sunset.IsZero() {
+		sunset = route.Sunset.UTC().Format(http.TimeFormat)
+		warning = `299 - "Deprecated API, sunset ` + sunset + `"`
+	}
+
+	var lastLogged, skipped int64
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Warning", warning)
+		if sunset != "" {
+			c.Header("Sunset", sunset)
+		}
+
+		now := time.Now().UnixNano()
+		last := atomic.LoadInt64(&lastLogged)
+		if now-last >= int64(deprecationLogInterval) && atomic.CompareAndSwapInt64(&lastLogged, last, now) {
+			event := w.config.Logger.Warn().
+				Str("method", route.Method).
+				Str("route", route.Path).
+				Str("clientIp", w.loggedClientIP(c)).
+				Int64("skipped", atomic.SwapInt64(&skipped, 0))
+			if sunset != "" {
+				event.Str("sunset", sunset)
+			}
+			event.Msg("Deprecated route is used")
+		} else {
+			atomic.AddInt64(&skipped, 1)
+		}
+
+		handler(c)
+	}
+}
@@ -0,0 +1,42 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebServer_DeprecatedRoute(t *testing.T) {
+	var logs bytes.Buffer
+
+	handler := func(c *gin.Context) { c.Status(http.StatusOK) }
+	webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/v1/items", Method: "GET", Deprecated: true, Sunset: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), Handler: handler},
+			{Path: "/v2/items", Method: "GET", Handler: handler},
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		w := serve(webServer, httptest.NewRequest("GET", "/v1/items", nil))
+		if w.Header().Get("Deprecation") != "true" || w.Header().Get("Sunset") != "Tue, 01 Jan 2030 00:00:00 GMT" ||
+			!strings.HasPrefix(w.Header().Get("Warning"), "299 - ") {
+			t.Fatalf("Wrong deprecation headers: %v", w.Header())
+		}
+	}
+	if w := serve(webServer, httptest.NewRequest("GET", "/v2/items", nil)); w.Header().Get("Deprecation") != "" {
+		t.Fatalf("Not deprecated route has the deprecation header")
+	}
+
+	if n := strings.Count(logs.String(), "Deprecated route is used"); n != 1 {
+		t.Fatalf("Deprecated route usage is logged %v times, expected once", n)
+	}
+	if !strings.Contains(logs.String(), `"route":"/v1/items"`) {
+		t.Fatalf("Deprecated route isn't logged: %v", logs.String())
+	}
+}
@@ -0,0 +1,74 @@
+package webserver
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// ErrorRenderer writes the error response, err may be nil
+type ErrorRenderer func(c *gin.Context, status int, err error)
+
+// ErrorRendererService is implemented by the services rendering the errors of their routes
+// differently from the global WebServerConfig.ErrorRenderer
+type ErrorRendererService interface {
+	ErrorRenderer() ErrorRenderer
+}
+
+// defaultErrorRenderer responds {"error": "<message>", "requestID": <id>} with the id in CorrelationHeader too,
+// so the clients can report it. The id is the incoming correlation ID (string) or requestID (number) if there is none,
+// the body field name is ErrorRequestIDField
+func defaultErrorRenderer(c *gin.Context, status int, err error) {
+	message := http.StatusText(status)
+	if err != nil {
+		message = err.Error()
+	}
+
+	field, header := "requestID", "X-Request-Id"
+	if v, ok := c.Get("webServer"); ok {
+		w := v.(*WebServer)
+		field, header = w.config.ErrorRequestIDField, w.config.CorrelationHeader
+	}
+	var id interface{} = RequestID(c)
+	if incoming := requestHeader(c, header); incoming != "" {
+		id = incoming
+	}
+	c.Header(header, fmt.Sprint(id))
+	c.JSON(status, gin.H{"error": message, field: id})
+}
+
+// RenderError aborts the request and renders the error with the renderer of the route's service,
+// the global one is used if the service doesn't define it
+func RenderError(c *gin.Context, status int, err error) {
+	c.Abort()
+	if err != nil {
+		_ = c.Error(err)
+	}
+	if v, ok := c.Get("errorRenderer"); ok {
+		v.(ErrorRenderer)(c, status, err)
+		return
+	}
+	if v, ok := c.Get("webServer"); ok {
+		if renderer := v.(*WebServer).config.ErrorRenderer; renderer != nil {
+			renderer(c, status, err)
+			return
+		}
+	}
+	defaultErrorRenderer(c, status, err)
+}
+
+// scopedErrorRenderer makes the service's error renderer seen by RenderError in the route handler
+func scopedErrorRenderer(service WebService, handler gin.HandlerFunc) gin.HandlerFunc {
+	s, ok := service.(ErrorRendererService)
+	if !ok {
+		return handler
+	}
+	renderer := s.ErrorRenderer()
+	if renderer == nil {
+		return handler
+	}
+	return func(c *gin.Context) {
+		c.Set("errorRenderer", renderer)
+		handler(c)
+	}
+}
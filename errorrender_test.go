@@ -0,0 +1,94 @@
+package webserver
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testAdminService struct {
+	testWebService
+}
+
+func (s *testAdminService) ErrorRenderer() ErrorRenderer {
+	return func(c *gin.Context, status int, err error) {
+		c.String(status, "admin error: %v", err)
+	}
+}
+
+func TestWebServer_ErrorRenderers(t *testing.T) {
+	failing := func(c *gin.Context) {
+		RenderError(c, http.StatusConflict, errors.New("conflict"))
+	}
+
+	webServer := newTestWebServer(t, WebServerConfig{
+		ErrorRenderer: func(c *gin.Context, status int, err error) {
+			c.JSON(status, gin.H{"message": err.Error(), "code": status})
+		},
+	}, &bytes.Buffer{})
+	webServer.ServiceRegister("/api", &testWebService{
+		routes: []WebRoute{{Path: "/item", Method: "GET", Handler: failing}},
+	})
+	webServer.ServiceRegister("/admin", &testAdminService{testWebService{
+		routes:    []WebRoute{{Path: "/item", Method: "GET", Handler: failing}},
+		altRoutes: []WebRoute{{Path: "^/admin/alt/.*", Method: "GET", Handler: failing}},
+	}})
+	webServer.ServiceRegisterVersioned("v1", &testAdminService{testWebService{
+		routes: []WebRoute{{Path: "/item", Method: "GET", Handler: failing}},
+	}})
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/api/item", `{"code":409,"message":"conflict"}`},
+		{"/admin/item", "admin error: conflict"},
+		{"/admin/alt/item", "admin error: conflict"},
+		{"/v1/item", "admin error: conflict"},
+		{"/latest/item", "admin error: conflict"},
+	}
+	for _, test := range tests {
+		w := serve(webServer, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != http.StatusConflict || w.Body.String() != test.body {
+			t.Fatalf("%v: wrong error response: %v %q", test.path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRenderError_Default(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{{Path: "/test", Method: "GET", Handler: func(c *gin.Context) {
+			RenderError(c, http.StatusNotFound, nil)
+		}}},
+	})
+
+	w := serve(webServer, httptest.NewRequest("GET", "/test", nil))
+	if w.Code != http.StatusNotFound || w.Body.String() != `{"error":"Not Found","requestID":1}` || w.Header().Get("X-Request-Id") != "1" {
+		t.Fatalf("Wrong default error response: %v %q %v", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+func TestRenderError_RequestID(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{ErrorRequestIDField: "traceId", CorrelationHeader: "x-correlation-id"}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{{Path: "/test", Method: "GET", Handler: func(c *gin.Context) {
+			RenderError(c, http.StatusBadRequest, errors.New("bad input"))
+		}}},
+	})
+
+	w := serve(webServer, httptest.NewRequest("GET", "/test", nil))
+	if w.Body.String() != `{"error":"bad input","traceId":1}` || w.Header().Get("X-Correlation-Id") != "1" {
+		t.Fatalf("Wrong error response without correlation ID: %q %v", w.Body.String(), w.Header())
+	}
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Correlation-Id", "abc-123")
+	w = serve(webServer, req)
+	if w.Body.String() != `{"error":"bad input","traceId":"abc-123"}` || w.Header().Get("X-Correlation-Id") != "abc-123" {
+		t.Fatalf("Wrong error response with correlation ID: %q %v", w.Body.String(), w.Header())
+	}
+}
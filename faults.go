@@ -0,0 +1,62 @@
+package webserver
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// FaultInjectionEnv is the environment variable that must be set to "allow" for the default fault injection guard to pass
+const FaultInjectionEnv = "WEBSERVER_FAULT_INJECTION"
+
+var ErrFaultInjectionNotAllowed = errors.New("fault injection is enabled but isn't allowed by the guard")
+
+// FaultInjection injects the latency and the errors into the requests for chaos testing.
+// It does nothing unless Enabled and the Guard allows it; the default guard requires FaultInjectionEnv set to "allow"
+// so a config leaked into production doesn't break it silently (NewWebServer fails instead)
+type FaultInjection struct {
+	Enabled bool
+	Guard   func() bool
+	// Probability is the fraction of the requests to inject the fault into, in the range [0, 1]
+	Probability float64
+	// Header forces the fault injection into the requests having it, regardless of Probability
+	Header string
+	// Delay is added before the request is handled
+	Delay time.Duration
+	// Status aborts the request with this status after the delay, zero means the delay only
+	Status int
+}
+
+func defaultFaultInjectionGuard() bool {
+	return os.Getenv(FaultInjectionEnv) == "allow"
+}
+
+// faultInjector delays and/or aborts the requests picked by the FaultInjection config. Health paths are never affected
+func (w *WebServer) faultInjector(faults FaultInjection) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if w.isHealthPath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		forced := faults.Header != "" && c.Request.Header.Get(faults.Header) != ""
+		if !forced && rand.Float64() >= faults.Probability {
+			c.Next()
+			return
+		}
+
+		c.Set("faultInjected", true)
+		if faults.Delay > 0 {
+			select {
+			case <-time.After(faults.Delay):
+			case <-c.Request.Context().Done():
+			}
+		}
+		if faults.Status != 0 {
+			c.AbortWithStatus(faults.Status)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,64 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newFaultsTestWebServer(t *testing.T, faults FaultInjection) *WebServer {
+	faults.Enabled = true
+	faults.Guard = func() bool { return true }
+	webServer := newTestWebServer(t, WebServerConfig{FaultInjection: faults}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/test", Method: "GET", Handler: func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			}},
+		},
+	})
+	return webServer
+}
+
+func TestWebServer_FaultInjectionRate(t *testing.T) {
+	webServer := newFaultsTestWebServer(t, FaultInjection{Probability: 0.3, Status: http.StatusServiceUnavailable})
+
+	const total = 2000
+	faults := 0
+	for i := 0; i < total; i++ {
+		if serve(webServer, httptest.NewRequest("GET", "/test", nil)).Code == http.StatusServiceUnavailable {
+			faults++
+		}
+	}
+	if rate := float64(faults) / total; rate < 0.25 || rate > 0.35 {
+		t.Fatalf("Injected faults rate %v is far from the configured 0.3", rate)
+	}
+}
+
+func TestWebServer_FaultInjectionHeader(t *testing.T) {
+	webServer := newFaultsTestWebServer(t, FaultInjection{Header: "X-Chaos", Delay: time.Millisecond * 50, Status: http.StatusBadGateway})
+
+	if code := serve(webServer, httptest.NewRequest("GET", "/test", nil)).Code; code != http.StatusOK {
+		t.Fatalf("Fault is injected without the header: %v", code)
+	}
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Chaos", "1")
+	start := time.Now()
+	if code := serve(webServer, req).Code; code != http.StatusBadGateway {
+		t.Fatalf("Fault isn't injected with the header: %v", code)
+	}
+	if time.Since(start) < time.Millisecond*50 {
+		t.Fatalf("Latency isn't injected")
+	}
+}
+
+func TestWebServer_FaultInjectionGuard(t *testing.T) {
+	_, err := NewWebServer(WebServerConfig{FaultInjection: FaultInjection{Enabled: true, Probability: 1}})
+	if err != ErrFaultInjectionNotAllowed {
+		t.Fatalf("Fault injection isn't guarded: %v", err)
+	}
+}
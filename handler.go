@@ -0,0 +1,48 @@
+package webserver
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Handler returns the http handler of the webserver: gin engine wrapped with the features
+// applied before the routing. It's used by Run and RunBg and may be mounted to a custom http.Server
+func (w *WebServer) Handler() http.Handler {
+	//the routing is resolved per request, so ReplaceServices takes effect on the running server
+	handler := http.Handler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		w.served.Load().(*routing).gin.Handler().ServeHTTP(rw, r)
+	}))
+	if w.config.CaseInsensitiveRoutes {
+		handler = w.lowercasePath(handler, w.config.CaseInsensitiveRedirect)
+	}
+	if len(w.config.StripResponseHeaders) > 0 {
+		handler = w.stripHeaders(handler)
+	}
+	if w.config.HTTPSRedirect {
+		handler = w.httpsRedirect(handler)
+	}
+	return handler
+}
+
+// lowercasePath makes the routing case-insensitive by lowercasing the request path
+// or redirecting the client to the lowercase path (308 for the methods other than GET and HEAD to keep the method and body)
+func (w *WebServer) lowercasePath(next http.Handler, redirect bool) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		lower := strings.ToLower(r.URL.Path)
+		if lower != r.URL.Path {
+			if redirect {
+				u := *r.URL
+				u.Path, u.RawPath = lower, ""
+				status := http.StatusMovedPermanently
+				if r.Method != http.MethodGet && r.Method != http.MethodHead {
+					status = http.StatusPermanentRedirect
+				}
+				http.Redirect(rw, r, u.RequestURI(), status)
+				return
+			}
+			r.URL.Path = lower
+			r.URL.RawPath = strings.ToLower(r.URL.RawPath)
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
@@ -0,0 +1,63 @@
+package webserver
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// headerStripWriter deletes the headers right before they are sent
+type headerStripWriter struct {
+	http.ResponseWriter
+	headers     []string
+	wroteHeader bool
+}
+
+func (sw *headerStripWriter) WriteHeader(code int) {
+	if !sw.wroteHeader {
+		sw.wroteHeader = true
+		header := sw.ResponseWriter.Header()
+		for _, name := range sw.headers {
+			header.Del(name)
+		}
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *headerStripWriter) Write(data []byte) (int, error) {
+	if !sw.wroteHeader {
+		sw.WriteHeader(http.StatusOK)
+	}
+	return sw.ResponseWriter.Write(data)
+}
+
+func (sw *headerStripWriter) Flush() {
+	if !sw.wroteHeader {
+		sw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (sw *headerStripWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := sw.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("response writer doesn't support hijacking")
+}
+
+func (sw *headerStripWriter) CloseNotify() <-chan bool {
+	if cn, ok := sw.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// stripHeaders removes StripResponseHeaders from all the responses, including the ones written by gin itself
+func (w *WebServer) stripHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&headerStripWriter{ResponseWriter: rw, headers: w.config.StripResponseHeaders}, r)
+	})
+}
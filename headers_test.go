@@ -0,0 +1,40 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebServer_StripResponseHeaders(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{StripResponseHeaders: []string{"server", "X-Powered-By"}}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/test", Method: "GET", Handler: func(c *gin.Context) {
+				c.Header("Server", "nginx/1.0")
+				c.Header("X-Powered-By", "Go")
+				c.Header("X-Kept", "1")
+				c.String(http.StatusOK, "body")
+			}},
+			{Path: "/empty", Method: "GET", Handler: func(c *gin.Context) {
+				c.Header("Server", "nginx/1.0")
+				c.Status(http.StatusNoContent)
+			}},
+		},
+	})
+
+	w := serve(webServer, httptest.NewRequest("GET", "/test", nil))
+	if _, ok := w.Header()["Server"]; ok || w.Header().Get("X-Powered-By") != "" {
+		t.Fatalf("Headers aren't stripped: %v", w.Header())
+	}
+	if w.Header().Get("X-Kept") != "1" || w.Body.String() != "body" {
+		t.Fatalf("Response is broken: %v %q", w.Header(), w.Body.String())
+	}
+
+	w = serve(webServer, httptest.NewRequest("GET", "/empty", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Server") != "" {
+		t.Fatalf("Headers aren't stripped from the response without body: %v %v", w.Code, w.Header())
+	}
+}
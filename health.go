@@ -0,0 +1,132 @@
+package webserver
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+type readinessChecks struct {
+	sync.Mutex
+	checks []readinessCheck
+}
+
+// Ready finishes the warmup phase, the webserver starts serving all requests
+func (w *WebServer) Ready() {
+	atomic.StoreInt32(&w.ready, 1)
+}
+
+// IsReady reports whether the warmup phase is finished.
+// WarmupCheck (if defined) is called on every check until it passes
+func (w *WebServer) IsReady() bool {
+	if atomic.LoadInt32(&w.ready) == 1 {
+		return true
+	}
+	if w.config.WarmupCheck != nil && w.config.WarmupCheck() {
+		w.Ready()
+		return true
+	}
+	return false
+}
+
+func (w *WebServer) isHealthPath(path string) bool {
+	return path != "" && (path == w.config.LivenessPath || path == w.config.ReadinessPath)
+}
+
+// warmupGate responds 503 to all but health requests until the webserver is ready
+func (w *WebServer) warmupGate() gin.HandlerFunc {
+	retryAfter := strconv.Itoa(int(w.config.WarmupRetryAfter.Seconds()))
+	return func(c *gin.Context) {
+		if !w.IsReady() && !w.isHealthPath(c.Request.URL.Path) {
+			c.Header("Retry-After", retryAfter)
+			c.AbortWithStatus(http.StatusServiceUnavailable)
+			return
+		}
+		c.Next()
+	}
+}
+
+func (w *WebServer) healthRegister(engine *gin.Engine) {
+	if w.config.LivenessPath != "" {
+		engine.GET(w.config.LivenessPath, func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+	}
+	if w.config.ReadinessPath != "" {
+		engine.GET(w.config.ReadinessPath, func(c *gin.Context) {
+			if !w.IsReady() {
+				c.String(http.StatusServiceUnavailable, "WARMUP")
+				return
+			}
+			if w.readinessChecks.empty() {
+				c.String(http.StatusOK, "OK")
+				return
+			}
+			status, results := w.runReadinessChecks(c.Request.Context())
+			c.JSON(status, results)
+		})
+	}
+}
+
+// AddReadinessCheck adds the named sub-check run by the readiness endpoint once the warmup is finished.
+// The checks run concurrently limited by ReadinessCheckTimeout, the endpoint responds 503 if any of them fails
+// and reports the per-check status in the JSON body: {"status": "fail", "checks": {"db": "ok", "cache": "<error>"}}
+func (w *WebServer) AddReadinessCheck(name string, check func(ctx context.Context) error) {
+	w.readinessChecks.Lock()
+	defer w.readinessChecks.Unlock()
+	w.readinessChecks.checks = append(w.readinessChecks.checks, readinessCheck{name, check})
+}
+
+func (rc *readinessChecks) empty() bool {
+	rc.Lock()
+	defer rc.Unlock()
+	return len(rc.checks) == 0
+}
+
+func (w *WebServer) runReadinessChecks(ctx context.Context) (int, gin.H) {
+	w.readinessChecks.Lock()
+	checks := append([]readinessCheck(nil), w.readinessChecks.checks...)
+	w.readinessChecks.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, w.config.ReadinessCheckTimeout)
+	defer cancel()
+
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check readinessCheck) {
+			defer wg.Done()
+			done := make(chan error, 1)
+			go func() {
+				done <- check.check(ctx)
+			}()
+			//a check ignoring the context doesn't hold the endpoint
+			select {
+			case errs[i] = <-done:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	status, overall := http.StatusOK, "ok"
+	results := make(map[string]string, len(checks))
+	for i, check := range checks {
+		results[check.name] = "ok"
+		if errs[i] != nil {
+			results[check.name] = errs[i].Error()
+			status, overall = http.StatusServiceUnavailable, "fail"
+		}
+	}
+	return status, gin.H{"status": overall, "checks": results}
+}
@@ -0,0 +1,95 @@
+package webserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebServer_Warmup(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{
+		Warmup:        true,
+		LivenessPath:  "/healthz",
+		ReadinessPath: "/readyz",
+	}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	rec := serve(webServer, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Wrong status code during warmup: %v", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("Wrong Retry-After header: %q", rec.Header().Get("Retry-After"))
+	}
+	if rec = serve(webServer, httptest.NewRequest("GET", "/healthz", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("Liveness isn't available during warmup: %v", rec.Code)
+	}
+	if rec = serve(webServer, httptest.NewRequest("GET", "/readyz", nil)); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Wrong readiness status during warmup: %v", rec.Code)
+	}
+
+	webServer.Ready()
+
+	if rec = serve(webServer, httptest.NewRequest("GET", "/", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("Wrong status code after warmup: %v", rec.Code)
+	}
+	if rec = serve(webServer, httptest.NewRequest("GET", "/readyz", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("Wrong readiness status after warmup: %v", rec.Code)
+	}
+}
+
+func TestWebServer_WarmupCheck(t *testing.T) {
+	var logs bytes.Buffer
+	warm := false
+
+	webServer := newTestWebServer(t, WebServerConfig{
+		Warmup:      true,
+		WarmupCheck: func() bool { return warm },
+	}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	if rec := serve(webServer, httptest.NewRequest("GET", "/", nil)); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Wrong status code during warmup: %v", rec.Code)
+	}
+	warm = true
+	if rec := serve(webServer, httptest.NewRequest("GET", "/", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("Wrong status code after warmup check passed: %v", rec.Code)
+	}
+}
+
+func TestWebServer_ReadinessChecks(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{ReadinessPath: "/readyz", ReadinessCheckTimeout: time.Millisecond * 100}, &bytes.Buffer{})
+	webServer.AddReadinessCheck("db", func(ctx context.Context) error {
+		return nil
+	})
+	webServer.AddReadinessCheck("cache", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	webServer.AddReadinessCheck("queue", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	rec := serve(webServer, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Wrong readiness status with a failing check: %v", rec.Code)
+	}
+	var body struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Can't decode readiness body %q: %v", rec.Body.String(), err)
+	}
+	if body.Status != "fail" || body.Checks["db"] != "ok" || body.Checks["cache"] != "connection refused" ||
+		body.Checks["queue"] != context.DeadlineExceeded.Error() {
+		t.Fatalf("Wrong readiness body: %+v", body)
+	}
+}
@@ -0,0 +1,69 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+// ResponseInfo is a snapshot of the request/response metadata passed to the after-response hooks
+type ResponseInfo struct {
+	RequestID  uint64
+	Method     string
+	Path       string
+	Route      string
+	ClientIP   string
+	StatusCode int
+	BodySize   int
+	Latency    time.Duration
+	// Keys is a copy of the context keys
+	Keys map[string]interface{}
+}
+
+type afterResponseHook struct {
+	fn    func(info ResponseInfo)
+	async bool
+}
+
+type afterResponseHooks struct {
+	hooks []afterResponseHook
+}
+
+// AfterResponse registers the hook called once the handlers have finished. Only the async hook doesn't delay
+// the client: it runs in a goroutine tracked by Shutdown. The sync one runs in the request goroutine
+// and the response isn't completed until it returns. The async hooks of the requests finished after Shutdown
+// has drained the connections (e.g. cut by its deadline) are skipped. Hooks must be registered before the webserver is started
+func (w *WebServer) AfterResponse(hook func(info ResponseInfo), async bool) {
+	w.afterResponse.hooks = append(w.afterResponse.hooks, afterResponseHook{hook, async})
+}
+
+func (w *WebServer) runAfterResponse(c *gin.Context, latency time.Duration) {
+	info := ResponseInfo{
+		RequestID:  RequestID(c),
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		Route:      c.FullPath(),
+		ClientIP:   w.loggedClientIP(c),
+		StatusCode: c.Writer.Status(),
+		BodySize:   c.Writer.Size(),
+		Latency:    latency,
+		Keys:       make(map[string]interface{}, len(c.Keys)),
+	}
+	for k, v := range c.Keys {
+		info.Keys[k] = v
+	}
+
+	for _, hook := range w.afterResponse.hooks {
+		if hook.async {
+			if !w.lifecycle.start("after-response hook") {
+				w.config.Logger.Warn().Uint64("requestID", info.RequestID).Msg("after-response hook is skipped on shutdown")
+				continue
+			}
+			go func(fn func(info ResponseInfo)) {
+				defer w.lifecycle.finish("after-response hook")
+				fn(info)
+			}(hook.fn)
+			continue
+		}
+		hook.fn(info)
+	}
+}
@@ -0,0 +1,65 @@
+package webserver
+
+import (
+	"bytes"
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebServer_AfterResponse(t *testing.T) {
+	var logs bytes.Buffer
+	var mu sync.Mutex
+	var syncInfo, asyncInfo ResponseInfo
+	release := make(chan struct{})
+
+	webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/items/:id", Method: "GET", Handler: func(c *gin.Context) {
+				time.Sleep(time.Millisecond * 20)
+				c.Set("user", "user42")
+				c.String(http.StatusCreated, "CREATED")
+			}},
+		},
+	})
+	webServer.AfterResponse(func(info ResponseInfo) {
+		syncInfo = info
+	}, false)
+	webServer.AfterResponse(func(info ResponseInfo) {
+		<-release
+		mu.Lock()
+		asyncInfo = info
+		mu.Unlock()
+	}, true)
+
+	rec := serve(webServer, httptest.NewRequest("GET", "/items/1", nil))
+	if rec.Code != http.StatusCreated || rec.Body.String() != "CREATED" {
+		t.Fatalf("Wrong answer: %v %v", rec.Code, rec.Body.String())
+	}
+
+	if syncInfo.StatusCode != http.StatusCreated || syncInfo.Route != "/items/:id" || syncInfo.RequestID != 1 {
+		t.Fatalf("Wrong response info: %+v", syncInfo)
+	}
+	if syncInfo.Latency < time.Millisecond*20 || syncInfo.Keys["user"] != "user42" {
+		t.Fatalf("Wrong response info latency or keys: %+v", syncInfo)
+	}
+
+	//shutdown waits for the async hooks
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		close(release)
+	}()
+	if err := webServer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if asyncInfo.StatusCode != http.StatusCreated || asyncInfo.Path != "/items/1" {
+		t.Fatalf("Async hook wasn't awaited on shutdown: %+v", asyncInfo)
+	}
+}
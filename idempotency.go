@@ -0,0 +1,162 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header carrying the client's idempotency key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type idempotencyRecord struct {
+	expires time.Time
+	done    bool
+	status  int
+	header  http.Header
+	body    []byte
+}
+
+// idempotencyCache keeps the recently seen idempotency keys until their TTL expires
+type idempotencyCache struct {
+	sync.Mutex
+	ttl       time.Duration
+	records   map[string]*idempotencyRecord
+	lastSweep time.Time
+}
+
+// seen returns the record of the key if it isn't expired, the new record is created otherwise
+func (ic *idempotencyCache) seen(key string) (record *idempotencyRecord, duplicate bool) {
+	ic.Lock()
+	defer ic.Unlock()
+
+	now := time.Now()
+	if now.Sub(ic.lastSweep) > ic.ttl {
+		for k, r := range ic.records {
+			if now.After(r.expires) {
+				delete(ic.records, k)
+			}
+		}
+		ic.lastSweep = now
+	}
+
+	if r, ok := ic.records[key]; ok && now.Before(r.expires) {
+		return r, true
+	}
+	record = &idempotencyRecord{expires: now.Add(ic.ttl)}
+	ic.records[key] = record
+	return record, false
+}
+
+// remove drops the record of the key unless it's already replaced
+func (ic *idempotencyCache) remove(key string, record *idempotencyRecord) {
+	ic.Lock()
+	defer ic.Unlock()
+	if ic.records[key] == record {
+		delete(ic.records, key)
+	}
+}
+
+func (ic *idempotencyCache) complete(record *idempotencyRecord, status int, header http.Header, body []byte) {
+	ic.Lock()
+	defer ic.Unlock()
+	record.done, record.status, record.header, record.body = true, status, header, body
+}
+
+// capturingWriter keeps a copy of the response body
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (cw *capturingWriter) Write(data []byte) (int, error) {
+	cw.body.Write(data)
+	return cw.ResponseWriter.Write(data)
+}
+
+func (cw *capturingWriter) WriteString(s string) (int, error) {
+	cw.body.WriteString(s)
+	return cw.ResponseWriter.WriteString(s)
+}
+
+// Idempotency returns a middleware tracking the Idempotency-Key of requests for the ttl.
+// A duplicate key (per client, method and path) is logged and flagged in the context as "idempotencyDuplicate".
+// The keys are scoped by the client IP (the peer address without TrustedProxies) unless the scope func is passed,
+// e.g. returning the authenticated principal.
+// If replay is set, the response of the original request is recorded and sent again to the duplicates
+// with the Idempotent-Replayed header (409 while the original request is in progress),
+// otherwise the duplicates are processed as usual. The failed (5xx or panicked) requests aren't recorded
+func (w *WebServer) Idempotency(ttl time.Duration, replay bool, scope ...func(c *gin.Context) string) gin.HandlerFunc {
+	cache := &idempotencyCache{
+		ttl:     ttl,
+		records: make(map[string]*idempotencyRecord),
+	}
+	scopeOf := w.clientIP
+	if len(scope) > 0 && scope[0] != nil {
+		scopeOf = scope[0]
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		cacheKey := scopeOf(c) + " " + c.Request.Method + " " + c.Request.URL.Path + " " + key
+		record, duplicate := cache.seen(cacheKey)
+		if duplicate {
+			c.Set("idempotencyDuplicate", true)
+			w.config.Logger.Warn().
+				Str("idempotencyKey", key).
+				Str("path", c.Request.URL.Path).
+				Str("method", c.Request.Method).
+				Uint64("requestID", RequestID(c)).
+				Bool("replay", replay).
+				Msg("duplicate idempotency key")
+
+			if !replay {
+				c.Next()
+				return
+			}
+
+			cache.Lock()
+			done, status, header, body := record.done, record.status, record.header, record.body
+			cache.Unlock()
+			if !done {
+				c.AbortWithStatus(http.StatusConflict)
+				return
+			}
+			for name, values := range header {
+				c.Writer.Header()[name] = values
+			}
+			c.Header("Idempotent-Replayed", "true")
+			c.Status(status)
+			_, _ = c.Writer.Write(body)
+			c.Abort()
+			return
+		}
+
+		if !replay {
+			c.Next()
+			return
+		}
+
+		writer := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		//the record is dropped if the handler panics or fails, so the request may be retried
+		completed := false
+		defer func() {
+			if !completed {
+				cache.remove(cacheKey, record)
+			}
+		}()
+		c.Next()
+		if writer.Status() < http.StatusInternalServerError {
+			cache.complete(record, writer.Status(), writer.Header().Clone(), writer.body.Bytes())
+			completed = true
+		}
+	}
+}
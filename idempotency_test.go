@@ -0,0 +1,159 @@
+package webserver
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebServer_Idempotency(t *testing.T) {
+	for _, replay := range []bool{false, true} {
+		var logs bytes.Buffer
+		calls := 0
+
+		webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+		webServer.ServiceRegister("", &testWebService{
+			routes: []WebRoute{
+				{Path: "/orders", Method: "POST", Handler: func(c *gin.Context) {
+					calls++
+					c.Header("X-Order", "created")
+					c.String(http.StatusCreated, "order %d, duplicate: %v", calls, c.GetBool("idempotencyDuplicate"))
+				}},
+			},
+			middlewares: []func(ctx *gin.Context){webServer.Idempotency(time.Minute, replay)},
+		})
+
+		post := func(key string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("POST", "/orders", nil)
+			req.Header.Set(IdempotencyKeyHeader, key)
+			return serve(webServer, req)
+		}
+
+		first := post("key-1")
+		second := post("key-1")
+		other := post("key-2")
+
+		if !strings.Contains(logs.String(), "duplicate idempotency key") || strings.Count(logs.String(), "key-1") != 1 {
+			t.Fatalf("Duplicate key isn't logged (replay: %v): %v", replay, logs.String())
+		}
+		if other.Body.String() != fmt.Sprintf("order %d, duplicate: false", calls) {
+			t.Fatalf("Request with another key is treated as duplicate (replay: %v): %v", replay, other.Body.String())
+		}
+
+		if replay {
+			if calls != 2 {
+				t.Fatalf("Duplicate request reached the handler: %v calls", calls)
+			}
+			if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() ||
+				second.Header().Get("X-Order") != "created" || second.Header().Get("Idempotent-Replayed") != "true" {
+				t.Fatalf("Wrong replayed response: %v %v %v", second.Code, second.Header(), second.Body.String())
+			}
+		} else {
+			if calls != 3 || second.Body.String() != "order 2, duplicate: true" {
+				t.Fatalf("Wrong warn-only duplicate processing: %v calls, %v", calls, second.Body.String())
+			}
+		}
+	}
+}
+
+func TestWebServer_IdempotencyScope(t *testing.T) {
+	var logs bytes.Buffer
+	calls := 0
+
+	webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/orders", Method: "POST", Handler: func(c *gin.Context) {
+				calls++
+				c.String(http.StatusCreated, "order %d for %v", calls, c.GetHeader("X-User"))
+			}},
+		},
+		middlewares: []func(ctx *gin.Context){webServer.Idempotency(time.Minute, true)},
+	})
+	byUser := newTestWebServer(t, WebServerConfig{}, &logs)
+	byUser.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/orders", Method: "POST", Handler: func(c *gin.Context) {
+				calls++
+				c.String(http.StatusCreated, "order %d for %v", calls, c.GetHeader("X-User"))
+			}},
+		},
+		middlewares: []func(ctx *gin.Context){byUser.Idempotency(time.Minute, true, func(c *gin.Context) string {
+			return c.GetHeader("X-User")
+		})},
+	})
+
+	post := func(webServer *WebServer, remoteAddr, user string) string {
+		req := httptest.NewRequest("POST", "/orders", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		req.Header.Set("X-User", user)
+		return serve(webServer, req).Body.String()
+	}
+
+	if first, other := post(webServer, "192.0.2.1:1234", "alice"), post(webServer, "192.0.2.2:1234", "bob"); first == other {
+		t.Fatalf("Response is replayed to another client: %v", other)
+	}
+	//the forwarded client IP from an untrusted peer doesn't change the scope
+	req := httptest.NewRequest("POST", "/orders", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	req.Header.Set("X-User", "mallory")
+	req.Header.Set("X-Forwarded-For", "203.0.113.1")
+	if replayed := serve(webServer, req).Header().Get("Idempotent-Replayed"); replayed != "true" {
+		t.Fatalf("Forwarded header changes the idempotency scope")
+	}
+	if first, other := post(byUser, "192.0.2.1:1234", "alice"), post(byUser, "192.0.2.1:1234", "bob"); first == other {
+		t.Fatalf("Response is replayed to another principal: %v", other)
+	}
+	if first, again := post(byUser, "192.0.2.1:1234", "carol"), post(byUser, "192.0.2.3:1234", "carol"); first != again {
+		t.Fatalf("Response isn't replayed to the same principal: %v, %v", first, again)
+	}
+}
+
+func TestWebServer_IdempotencyFailures(t *testing.T) {
+	var logs bytes.Buffer
+	calls := 0
+
+	webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/orders", Method: "POST", Handler: func(c *gin.Context) {
+				calls++
+				switch calls {
+				case 1:
+					panic("boom")
+				case 2:
+					c.String(http.StatusServiceUnavailable, "unavailable")
+				default:
+					c.String(http.StatusCreated, "order %d", calls)
+				}
+			}},
+		},
+		middlewares: []func(ctx *gin.Context){webServer.Idempotency(time.Minute, true)},
+	})
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/orders", nil)
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		return serve(webServer, req)
+	}
+
+	if rec := post(); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Wrong panicked response: %v", rec.Code)
+	}
+	if rec := post(); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Retry after the panic isn't processed: %v %v", rec.Code, rec.Body.String())
+	}
+	if rec := post(); rec.Code != http.StatusCreated || rec.Body.String() != "order 3" {
+		t.Fatalf("Retry after the 5xx response isn't processed: %v %v", rec.Code, rec.Body.String())
+	}
+	if rec := post(); rec.Body.String() != "order 3" || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("Successful response isn't replayed: %v %v", rec.Code, rec.Body.String())
+	}
+}
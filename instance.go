@@ -0,0 +1,29 @@
+package webserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/rs/zerolog"
+)
+
+func newInstanceID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// withInstanceID returns the child logger adding the instanceID field to the log lines
+func withInstanceID(logger *zerolog.Logger, instanceID string) *zerolog.Logger {
+	if logger == nil {
+		return nil
+	}
+	child := logger.With().Str("instanceID", instanceID).Logger()
+	return &child
+}
+
+// InstanceID returns the random ID generated at the webserver creation
+func (w *WebServer) InstanceID() string {
+	return w.instanceID
+}
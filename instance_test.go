@@ -0,0 +1,38 @@
+package webserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebServer_InstanceID(t *testing.T) {
+	var logs, otherLogs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{LogInstanceID: true}, &logs)
+	other := newTestWebServer(t, WebServerConfig{LogInstanceID: true}, &otherLogs)
+
+	if webServer.InstanceID() == "" || webServer.InstanceID() == other.InstanceID() {
+		t.Fatalf("Wrong instance IDs: %q and %q", webServer.InstanceID(), other.InstanceID())
+	}
+
+	webServer.ServiceRegister("", &PublicWebService{})
+	serve(webServer, httptest.NewRequest("GET", "/", nil))
+
+	var entry struct {
+		InstanceID string `json:"instanceID"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Can't decode access log %q: %v", logs.String(), err)
+	}
+	if entry.InstanceID != webServer.InstanceID() {
+		t.Fatalf("Wrong instanceID in the access log: %q", entry.InstanceID)
+	}
+
+	logs.Reset()
+	webServer.config.Logger.Info().Msg("app log line")
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil || entry.InstanceID != webServer.InstanceID() {
+		t.Fatalf("Wrong instanceID in the app log: %v", logs.String())
+	}
+}
@@ -0,0 +1,159 @@
+package webserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"time"
+)
+
+// isolatedWriter buffers the response of an isolated handler, so the handler
+// left running after the timeout can't write to the real connection
+type isolatedWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newIsolatedWriter() *isolatedWriter {
+	return &isolatedWriter{header: http.Header{}}
+}
+
+func (iw *isolatedWriter) Header() http.Header {
+	return iw.header
+}
+
+func (iw *isolatedWriter) WriteHeader(code int) {
+	if iw.status == 0 {
+		iw.status = code
+	}
+}
+
+func (iw *isolatedWriter) WriteHeaderNow() {
+	iw.WriteHeader(http.StatusOK)
+}
+
+func (iw *isolatedWriter) Write(data []byte) (int, error) {
+	iw.WriteHeaderNow()
+	return iw.body.Write(data)
+}
+
+func (iw *isolatedWriter) WriteString(s string) (int, error) {
+	iw.WriteHeaderNow()
+	return iw.body.WriteString(s)
+}
+
+func (iw *isolatedWriter) Status() int {
+	if iw.status == 0 {
+		return http.StatusOK
+	}
+	return iw.status
+}
+
+func (iw *isolatedWriter) Size() int {
+	if iw.status == 0 {
+		return -1
+	}
+	return iw.body.Len()
+}
+
+func (iw *isolatedWriter) Written() bool {
+	return iw.status != 0
+}
+
+func (iw *isolatedWriter) Flush() {}
+
+func (iw *isolatedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("isolated handler can't hijack the connection")
+}
+
+func (iw *isolatedWriter) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func (iw *isolatedWriter) Pusher() http.Pusher {
+	return nil
+}
+
+// copyTo sends the buffered response to the real writer, the declared trailers are set after the body
+func (iw *isolatedWriter) copyTo(w gin.ResponseWriter) {
+	trailers := declaredTrailers(iw.header)
+	for name, values := range iw.header {
+		if !trailers[name] {
+			w.Header()[name] = values
+		}
+	}
+	if iw.status != 0 {
+		w.WriteHeader(iw.status)
+	}
+	if iw.body.Len() > 0 {
+		_, _ = w.Write(iw.body.Bytes())
+	}
+	for name := range trailers {
+		if values, ok := iw.header[name]; ok {
+			w.Header()[name] = values
+		}
+	}
+}
+
+// isolatedHandler runs the handler in a separate goroutine, recovers its panic with 500
+// and responds 504 if the handler doesn't finish within the timeout (0 means no timeout).
+// Tradeoffs: the handler gets a copy of the context, so its context changes (Set, Abort) are not seen
+// by the outer middlewares; the response is buffered and sent once the handler returns, so streaming
+// and hijacking are not possible; a hung handler isn't killed but keeps running in background
+// until it notices the cancellation of c.Request.Context()
+func (w *WebServer) isolatedHandler(handler gin.HandlerFunc, timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		writer := newIsolatedWriter()
+		cp := c.Copy()
+		cp.Writer = writer
+		cp.Request = c.Request.WithContext(ctx)
+
+		panicked := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				panicked <- recover()
+			}()
+			handler(cp)
+		}()
+
+		select {
+		case p := <-panicked:
+			if p != nil {
+				w.config.Logger.Error().
+					Str("panic", fmt.Sprint(p)).
+					Str("path", c.Request.URL.Path).
+					Uint64("requestID", RequestID(c)).
+					Msg("isolated handler panic")
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			writer.copyTo(c.Writer)
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				w.config.Logger.Warn().
+					Dur("timeout", timeout).
+					Str("path", c.Request.URL.Path).
+					Uint64("requestID", RequestID(c)).
+					Msg("isolated handler timeout")
+				c.Set("timedOut", true)
+				c.AbortWithStatus(http.StatusGatewayTimeout)
+				return
+			}
+			//the client has gone
+			c.Abort()
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebServer_IsolatedRoute(t *testing.T) {
+	var logs bytes.Buffer
+	release := make(chan struct{})
+	defer close(release)
+
+	webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+	webServer.ServiceRegister("/plugins", &testWebService{
+		routes: []WebRoute{
+			{Path: "/good", Method: "GET", Isolated: true, IsolationTimeout: time.Second, Handler: func(c *gin.Context) {
+				c.Header("X-Plugin", "good")
+				c.String(http.StatusAccepted, "GOOD")
+			}},
+			{Path: "/panic", Method: "GET", Isolated: true, Handler: func(c *gin.Context) {
+				panic("plugin failure")
+			}},
+			{Path: "/hang", Method: "GET", Isolated: true, IsolationTimeout: time.Millisecond * 100, Handler: func(c *gin.Context) {
+				<-release
+				c.String(http.StatusOK, "TOO LATE")
+			}},
+		},
+	})
+
+	rec := serve(webServer, httptest.NewRequest("GET", "/plugins/good", nil))
+	if rec.Code != http.StatusAccepted || rec.Body.String() != "GOOD" || rec.Header().Get("X-Plugin") != "good" {
+		t.Fatalf("Wrong isolated handler response: %v %v %v", rec.Code, rec.Header(), rec.Body.String())
+	}
+
+	if rec = serve(webServer, httptest.NewRequest("GET", "/plugins/panic", nil)); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Wrong status of panicking handler: %v", rec.Code)
+	}
+
+	start := time.Now()
+	rec = serve(webServer, httptest.NewRequest("GET", "/plugins/hang", nil))
+	if rec.Code != http.StatusGatewayTimeout || rec.Body.String() == "TOO LATE" {
+		t.Fatalf("Wrong response of hanging handler: %v %v", rec.Code, rec.Body.String())
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("Hanging handler blocked the request for %v", time.Since(start))
+	}
+
+	if !bytes.Contains(logs.Bytes(), []byte("isolated handler panic")) || !bytes.Contains(logs.Bytes(), []byte("isolated handler timeout")) {
+		t.Fatalf("Isolated handler failures aren't logged: %v", logs.String())
+	}
+}
@@ -0,0 +1,20 @@
+package webserver
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// BufferedJSON renders the object as JSON with Content-Length set, so the client gets the body size upfront
+// and the response isn't chunked. Content-Length is dropped if the response is compressed
+func BufferedJSON(c *gin.Context, status int, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		RenderError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Header("Content-Length", strconv.Itoa(len(data)))
+	c.Data(status, gin.MIMEJSON+"; charset=utf-8", data)
+}
@@ -0,0 +1,43 @@
+package webserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestBufferedJSON(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{Compression: true}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{{Path: "/test", Method: "GET", Handler: func(c *gin.Context) {
+			BufferedJSON(c, http.StatusOK, gin.H{"items": []int{1, 2, 3}})
+		}}},
+	})
+
+	w := serve(webServer, httptest.NewRequest("GET", "/test", nil))
+	if w.Body.String() != `{"items":[1,2,3]}` || w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Fatalf("Wrong response: %q %v", w.Body.String(), w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("Content-Length") != strconv.Itoa(w.Body.Len()) {
+		t.Fatalf("Wrong Content-Length: %q", w.Header().Get("Content-Length"))
+	}
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = serve(webServer, req)
+	if w.Header().Get("Content-Length") != "" {
+		t.Fatalf("Uncompressed Content-Length is sent with the compressed response")
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, _ := ioutil.ReadAll(gz); string(decoded) != `{"items":[1,2,3]}` {
+		t.Fatalf("Wrong decompressed body: %q", decoded)
+	}
+}
@@ -0,0 +1,83 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type languageRange struct {
+	tag string
+	q   float64
+}
+
+// parseAcceptLanguage returns the language ranges of the Accept-Language header ordered by q-value,
+// malformed entries are skipped
+func parseAcceptLanguage(header string) []languageRange {
+	var ranges []languageRange
+	for _, entry := range strings.Split(header, ",") {
+		parts := strings.Split(entry, ";")
+		tag := strings.TrimSpace(parts[0])
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		valid := true
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			v, err := strconv.ParseFloat(param[2:], 64)
+			if err != nil || v < 0 || v > 1 {
+				valid = false
+				break
+			}
+			q = v
+		}
+		if valid && q > 0 {
+			ranges = append(ranges, languageRange{tag, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	return ranges
+}
+
+// matchLanguage returns the supported language matching the tag exactly or by the primary subtag
+func matchLanguage(tag string, supported []string) (string, bool) {
+	if tag == "*" {
+		return supported[0], true
+	}
+	for _, lang := range supported {
+		if strings.EqualFold(lang, tag) {
+			return lang, true
+		}
+	}
+	primary := strings.SplitN(tag, "-", 2)[0]
+	for _, lang := range supported {
+		if strings.EqualFold(strings.SplitN(lang, "-", 2)[0], primary) {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// PreferredLanguage returns the best of the supported languages according to the request Accept-Language header,
+// the first supported language is the default one. The result is also stored in the context as "language"
+func PreferredLanguage(c *gin.Context, supported []string) string {
+	if len(supported) == 0 {
+		return ""
+	}
+	language := supported[0]
+	for _, r := range parseAcceptLanguage(c.GetHeader("Accept-Language")) {
+		if lang, ok := matchLanguage(r.tag, supported); ok {
+			language = lang
+			break
+		}
+	}
+	c.Set("language", language)
+	return language
+}
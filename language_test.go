@@ -0,0 +1,40 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPreferredLanguage(t *testing.T) {
+	supported := []string{"en", "de", "ru-RU"}
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", "en"},
+		{"de", "de"},
+		{"fr;q=0.9, de;q=0.5, ru;q=0.7", "ru-RU"},
+		{"de;q=0.2, en;q=0.8", "en"},
+		{"fr, *;q=0.1", "en"},
+		{"ru-ru", "ru-RU"},
+		{"de-AT", "de"},
+		{"fr, it", "en"},
+		{"de;q=abc, ru", "ru-RU"},
+		{";;,, ;q=", "en"},
+		{"de;q=0, ru;q=0.1", "ru-RU"},
+	}
+
+	for _, test := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/", nil)
+		c.Request.Header.Set("Accept-Language", test.header)
+
+		if lang := PreferredLanguage(c, supported); lang != test.expected {
+			t.Fatalf("Wrong language for %q: %v, expected %v", test.header, lang, test.expected)
+		}
+		if c.GetString("language") != test.expected {
+			t.Fatalf("Language isn't stored in the context for %q", test.header)
+		}
+	}
+}
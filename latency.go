@@ -0,0 +1,81 @@
+package webserver
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyReservoirSize is the number of the latency samples kept per route
+const latencyReservoirSize = 1024
+
+// Percentiles is the latency snapshot of a route, Count is the total number of the observed requests
+type Percentiles struct {
+	Count uint64
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// latencyReservoir keeps a uniform random sample of the route latencies (reservoir sampling)
+type latencyReservoir struct {
+	count   uint64
+	samples []time.Duration
+}
+
+type latencyStats struct {
+	sync.Mutex
+	routes map[string]*latencyReservoir
+}
+
+func (ls *latencyStats) observe(route string, latency time.Duration) {
+	ls.Lock()
+	defer ls.Unlock()
+
+	if ls.routes == nil {
+		ls.routes = make(map[string]*latencyReservoir)
+	}
+	r, ok := ls.routes[route]
+	if !ok {
+		r = &latencyReservoir{}
+		ls.routes[route] = r
+	}
+	r.count++
+	if len(r.samples) < latencyReservoirSize {
+		r.samples = append(r.samples, latency)
+	} else if i := rand.Int63n(int64(r.count)); i < latencyReservoirSize {
+		r.samples[i] = latency
+	}
+}
+
+func (r *latencyReservoir) percentiles() Percentiles {
+	samples := make([]time.Duration, len(r.samples))
+	copy(samples, r.samples)
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	at := func(p float64) time.Duration {
+		return samples[int(p*float64(len(samples)-1)+0.5)]
+	}
+	return Percentiles{
+		Count: r.count,
+		P50:   at(0.5),
+		P90:   at(0.9),
+		P99:   at(0.99),
+		Max:   samples[len(samples)-1],
+	}
+}
+
+// LatencyStats returns the latency percentiles per route (gin full path) estimated from a sample of the recent requests.
+// The requests not matched a gin route and the not logged ones (NoLoggingPaths) are not counted
+func (w *WebServer) LatencyStats() map[string]Percentiles {
+	w.latency.Lock()
+	defer w.latency.Unlock()
+
+	stats := make(map[string]Percentiles, len(w.latency.routes))
+	for route, r := range w.latency.routes {
+		stats[route] = r.percentiles()
+	}
+	return stats
+}
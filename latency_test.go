@@ -0,0 +1,53 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLatencyStats_Percentiles(t *testing.T) {
+	var stats latencyStats
+	for i := 1; i <= 100; i++ {
+		stats.observe("/a", time.Duration(i)*time.Millisecond)
+	}
+	//overflow the reservoir, the sample must stay representative
+	for i := 0; i < latencyReservoirSize*4; i++ {
+		stats.observe("/b", time.Duration(i%100+1)*time.Millisecond)
+	}
+
+	p := stats.routes["/a"].percentiles()
+	if p.Count != 100 || p.P50 != 51*time.Millisecond || p.P90 != 90*time.Millisecond || p.P99 != 99*time.Millisecond || p.Max != 100*time.Millisecond {
+		t.Fatalf("Wrong percentiles: %+v", p)
+	}
+
+	p = stats.routes["/b"].percentiles()
+	if p.Count != latencyReservoirSize*4 {
+		t.Fatalf("Wrong count: %v", p.Count)
+	}
+	if p.P50 < 40*time.Millisecond || p.P50 > 60*time.Millisecond || p.P90 < 80*time.Millisecond {
+		t.Fatalf("Sampled percentiles are far from the real ones: %+v", p)
+	}
+}
+
+func TestWebServer_LatencyStats(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{{Path: "/items/:id", Method: "GET", Handler: func(c *gin.Context) {
+			time.Sleep(time.Millisecond * 10)
+			c.Status(http.StatusOK)
+		}}},
+	})
+
+	serve(webServer, httptest.NewRequest("GET", "/items/1", nil))
+	serve(webServer, httptest.NewRequest("GET", "/items/2", nil))
+	serve(webServer, httptest.NewRequest("GET", "/unknown", nil))
+
+	stats := webServer.LatencyStats()
+	if len(stats) != 1 || stats["/items/:id"].Count != 2 || stats["/items/:id"].P50 < 10*time.Millisecond {
+		t.Fatalf("Wrong latency stats: %+v", stats)
+	}
+}
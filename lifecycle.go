@@ -0,0 +1,110 @@
+package webserver
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// lifecycle tracks the background work bound to the webserver lifetime (periodic tasks, async after-response hooks)
+// with a single WaitGroup, Shutdown awaits it and reports the work still pending when its deadline is hit
+type lifecycle struct {
+	sync.WaitGroup
+	mu      sync.Mutex
+	pending map[string]int
+	// closed refuses the new work once Shutdown awaits the WaitGroup
+	closed bool
+	// ctx is canceled on the periodic tasks shutdown phase
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newLifecycle() *lifecycle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &lifecycle{pending: make(map[string]int), ctx: ctx, cancel: cancel}
+}
+
+// start tracks the new work, it reports false if the lifecycle is stopped and the work must not be started
+func (l *lifecycle) start(name string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return false
+	}
+	l.pending[name]++
+	l.Add(1)
+	return true
+}
+
+func (l *lifecycle) finish(name string) {
+	l.mu.Lock()
+	if l.pending[name]--; l.pending[name] <= 0 {
+		delete(l.pending, name)
+	}
+	l.mu.Unlock()
+	l.Done()
+}
+
+// pendingWork returns the names of the work still running with the counts: "after-response hook x2"
+func (l *lifecycle) pendingWork() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	pending := make([]string, 0, len(l.pending))
+	for name, count := range l.pending {
+		if count > 1 {
+			name += " x" + strconv.Itoa(count)
+		}
+		pending = append(pending, name)
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+// stop refuses the new work and cancels the periodic tasks
+func (l *lifecycle) stop() {
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+	l.cancel()
+}
+
+// wait blocks until all the work is finished or the context is done, it reports whether the work is finished
+func (l *lifecycle) wait(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		l.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// RunPeriodic runs the task every interval until the webserver is shut down or closed.
+// The task context is canceled once the connections are drained and Shutdown awaits the running task,
+// the tasks added after that aren't run
+func (w *WebServer) RunPeriodic(name string, interval time.Duration, task func(ctx context.Context)) {
+	name = "periodic task " + name
+	if !w.lifecycle.start(name) {
+		w.config.Logger.Warn().Str("task", name).Msg("periodic task isn't started on shutdown")
+		return
+	}
+	go func() {
+		defer w.lifecycle.finish(name)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.lifecycle.ctx.Done():
+				return
+			case <-ticker.C:
+				task(w.lifecycle.ctx)
+			}
+		}
+	}()
+}
@@ -0,0 +1,180 @@
+package webserver
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebServer_ShutdownPhases(t *testing.T) {
+	var logs syncBuffer
+	var served, hookDone, taskDone int32
+	body := make(chan string, 1)
+	entered := make(chan struct{})
+
+	webServer := newTestWebServer(t, WebServerConfig{Port: 9101}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/slow", Method: "GET", Handler: func(c *gin.Context) {
+				close(entered)
+				time.Sleep(time.Millisecond * 100)
+				atomic.StoreInt32(&served, 1)
+				c.String(http.StatusOK, "SLOW")
+			}},
+		},
+	})
+	webServer.AfterResponse(func(info ResponseInfo) {
+		time.Sleep(time.Millisecond * 50)
+		atomic.StoreInt32(&hookDone, 1)
+	}, true)
+	webServer.RunPeriodic("cleanup", time.Millisecond*10, func(ctx context.Context) {
+		<-ctx.Done()
+		//the task is canceled only after the in-flight request is served
+		if atomic.LoadInt32(&served) == 1 {
+			atomic.StoreInt32(&taskDone, 1)
+		}
+	})
+
+	if err := webServer.RunBg(); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		resp, err := http.Get("http://localhost:9101/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-entered
+
+	if err := webServer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if b := <-body; b != "SLOW" {
+		t.Fatalf("In-flight request isn't drained: %v", b)
+	}
+	if atomic.LoadInt32(&taskDone) != 1 {
+		t.Fatalf("Periodic task isn't canceled after the connections are drained")
+	}
+	if atomic.LoadInt32(&hookDone) != 1 {
+		t.Fatalf("After-response hook isn't awaited")
+	}
+	if strings.Contains(logs.String(), "still pending") {
+		t.Fatalf("Pending work is logged: %v", logs.String())
+	}
+}
+
+func TestWebServer_ShutdownDeadline(t *testing.T) {
+	var logs syncBuffer
+	release := make(chan struct{})
+	defer close(release)
+	entered := make(chan struct{})
+
+	webServer := newTestWebServer(t, WebServerConfig{Port: 9101}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/stuck", Method: "GET", Handler: func(c *gin.Context) {
+				close(entered)
+				<-release
+			}},
+			{Path: "/", Method: "GET", Handler: func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			}},
+		},
+	})
+	webServer.AfterResponse(func(info ResponseInfo) {
+		if info.Path == "/" {
+			<-release
+		}
+	}, true)
+	webServer.RunPeriodic("sync", time.Millisecond*10, func(ctx context.Context) {
+		//ignores the cancellation
+		<-release
+	})
+
+	if err := webServer.RunBg(); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get("http://localhost:9101/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	go func() {
+		if resp, err := http.Get("http://localhost:9101/stuck"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	start := time.Now()
+	if err := webServer.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Wrong shutdown error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Millisecond*500 {
+		t.Fatalf("Shutdown doesn't honor the deadline: %v", elapsed)
+	}
+	if webServer.IsRunning() {
+		t.Fatalf("Webserver is running after the forced close")
+	}
+
+	out := logs.String()
+	if !strings.Contains(out, "connections are still open on shutdown") {
+		t.Fatalf("Forced close isn't logged: %v", out)
+	}
+	if !strings.Contains(out, `"pending":["after-response hook","periodic task sync"]`) {
+		t.Fatalf("Pending work isn't logged: %v", out)
+	}
+}
+
+func TestWebServer_ShutdownRefusesWork(t *testing.T) {
+	var logs syncBuffer
+	var hookCalls, taskCalls int32
+	sink := &blockingWriter{release: make(chan struct{})}
+	defer close(sink.release)
+
+	webServer := newTestWebServer(t, WebServerConfig{LoggerHttp: newTestLogger(sink), AsyncLogBuffer: 16}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+	webServer.AfterResponse(func(info ResponseInfo) {
+		atomic.AddInt32(&hookCalls, 1)
+	}, true)
+	serve(webServer, httptest.NewRequest("GET", "/", nil))
+
+	//the stalled access log sink doesn't hold the shutdown past its deadline
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	start := time.Now()
+	_ = webServer.Shutdown(ctx)
+	if elapsed := time.Since(start); elapsed > time.Millisecond*500 {
+		t.Fatalf("Shutdown doesn't honor the deadline: %v", elapsed)
+	}
+
+	//the request finished after the shutdown (e.g. cut by the forced close) doesn't start the hooks
+	serve(webServer, httptest.NewRequest("GET", "/", nil))
+	webServer.RunPeriodic("late", time.Millisecond, func(ctx context.Context) {
+		atomic.AddInt32(&taskCalls, 1)
+	})
+	time.Sleep(time.Millisecond * 20)
+
+	if n := atomic.LoadInt32(&hookCalls); n != 1 {
+		t.Fatalf("Wrong number of hook calls: %v", n)
+	}
+	if n := atomic.LoadInt32(&taskCalls); n != 0 {
+		t.Fatalf("Periodic task is run after the shutdown: %v", n)
+	}
+	out := logs.String()
+	if !strings.Contains(out, "after-response hook is skipped on shutdown") || !strings.Contains(out, "periodic task isn't started on shutdown") {
+		t.Fatalf("Refused work isn't logged: %v", out)
+	}
+}
@@ -0,0 +1,140 @@
+package webserver
+
+import (
+	"compress/gzip"
+	"github.com/rs/zerolog"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogFile configures the access log file rotated by size and/or age, the rotated files are named
+// <Path>.<timestamp> and gzipped in background if Gzip is set
+type LogFile struct {
+	Path string
+	// MaxSize is the file size in bytes the file is rotated at, 100MB by default
+	MaxSize int64
+	// MaxAge rotates the file once it's written for longer, zero means no age based rotation
+	MaxAge time.Duration
+	Gzip   bool
+}
+
+// rotatingFile is the io.Writer of LogFile, it's opened lazily and reopened on write after Close
+type rotatingFile struct {
+	config LogFile
+	logger *zerolog.Logger
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+
+	compressing sync.WaitGroup
+}
+
+func newRotatingFile(config LogFile, logger *zerolog.Logger) (*rotatingFile, error) {
+	if config.MaxSize <= 0 {
+		config.MaxSize = 100 << 20
+	}
+	rf := &rotatingFile{config: config, logger: logger}
+	//fail early on a wrong path
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	file, err := os.OpenFile(rf.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	rf.file, rf.size, rf.opened = file, info.Size(), time.Now()
+	return nil
+}
+
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+	if rf.size > 0 && (rf.size+int64(len(p)) > rf.config.MaxSize ||
+		rf.config.MaxAge > 0 && time.Since(rf.opened) >= rf.config.MaxAge) {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *rotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	rf.file = nil
+	rotated := rf.config.Path + "." + time.Now().Format("20060102T150405.000000000")
+	if err := os.Rename(rf.config.Path, rotated); err != nil {
+		return err
+	}
+	if rf.config.Gzip {
+		rf.compressing.Add(1)
+		go func() {
+			defer rf.compressing.Done()
+			if err := gzipFile(rotated); err != nil {
+				rf.logger.Error().Err(err).Str("file", rotated).Msg("Can't gzip the rotated access log")
+			}
+		}()
+	}
+	return rf.open()
+}
+
+// Close closes the file and waits for the rotated files compression
+func (rf *rotatingFile) Close() (err error) {
+	rf.mu.Lock()
+	if rf.file != nil {
+		err = rf.file.Close()
+		rf.file = nil
+	}
+	rf.mu.Unlock()
+	rf.compressing.Wait()
+	return
+}
+
+// gzipFile replaces the file with its gzipped copy <path>.gz
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
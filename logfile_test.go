@@ -0,0 +1,82 @@
+package webserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webserver-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+	rf, err := newRotatingFile(LogFile{Path: path, MaxSize: 100, Gzip: true}, newTestLogger(ioutil.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := strings.Repeat("x", 39) + "\n"
+	for i := 0; i < 5; i++ {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	//5 lines of 40 bytes are split by 100 bytes: 2 + 2 rotated, 1 current
+	rotated, _ := filepath.Glob(path + ".*.gz")
+	if len(rotated) != 2 {
+		t.Fatalf("Wrong number of the rotated files: %v", rotated)
+	}
+	if uncompressed, _ := filepath.Glob(path + ".*[0-9]"); len(uncompressed) != 0 {
+		t.Fatalf("Rotated files aren't gzipped: %v", uncompressed)
+	}
+	for _, name := range rotated {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(gz)
+		f.Close()
+		if string(data) != line+line {
+			t.Fatalf("Wrong rotated file content: %q", data)
+		}
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != line {
+		t.Fatalf("Wrong current file content: %q", data)
+	}
+}
+
+func TestWebServer_AccessLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webserver-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+	webServer := newTestWebServer(t, WebServerConfig{AccessLogFile: &LogFile{Path: path}}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	serve(webServer, httptest.NewRequest("GET", "/", nil))
+	_ = webServer.Shutdown(context.Background())
+
+	if data, _ := ioutil.ReadFile(path); !strings.Contains(string(data), "http request") {
+		t.Fatalf("Access log isn't written to the file: %q", data)
+	}
+}
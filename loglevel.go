@@ -0,0 +1,89 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// loggedHeaderValues are the request headers whose values are logged in the debug request details by default,
+// the values of all the other headers are redacted
+var loggedHeaderValues = []string{"Accept", "Accept-Encoding", "Accept-Language", "Cache-Control", "Content-Length",
+	"Content-Type", "Host", "If-Modified-Since", "If-None-Match", "Referer", "User-Agent"}
+
+// logLevelOverride is the temporary level of the http logger
+type logLevelOverride struct {
+	mu         sync.Mutex
+	generation uint64
+	timer      *time.Timer
+	// level is 0 if not overridden, the zerolog level + 2 otherwise (trace level is -1)
+	level int32
+}
+
+// SetAccessLogLevel temporarily sets the level of the http logger, it's reverted after the duration
+// (0 means until ResetAccessLogLevel). At debug level the http logger adds the request headers line
+// (only the values of the allow-listed headers are logged, see LogHeaderValues). zerolog global level still applies
+func (w *WebServer) SetAccessLogLevel(level zerolog.Level, duration time.Duration) {
+	o := &w.accessLogLevel
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.timer != nil {
+		o.timer.Stop()
+	}
+	o.generation++
+	atomic.StoreInt32(&o.level, int32(level)+2)
+	w.config.Logger.Info().Str("level", level.String()).Dur("duration", duration).Msg("Access log level is set")
+
+	if duration > 0 {
+		generation := o.generation
+		o.timer = time.AfterFunc(duration, func() {
+			o.mu.Lock()
+			defer o.mu.Unlock()
+			//the timer stopped too late must not reset a newer level
+			if o.generation == generation {
+				atomic.StoreInt32(&o.level, 0)
+				w.config.Logger.Info().Msg("Access log level is reverted")
+			}
+		})
+	}
+}
+
+// ResetAccessLogLevel reverts the level set by SetAccessLogLevel
+func (w *WebServer) ResetAccessLogLevel() {
+	o := &w.accessLogLevel
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.timer != nil {
+		o.timer.Stop()
+	}
+	o.generation++
+	atomic.StoreInt32(&o.level, 0)
+}
+
+// accessLogger returns the http logger with the level override applied
+func (w *WebServer) accessLogger(logger *zerolog.Logger) *zerolog.Logger {
+	level := atomic.LoadInt32(&w.accessLogLevel.level)
+	if level == 0 {
+		return logger
+	}
+	l := logger.Level(zerolog.Level(level - 2))
+	return &l
+}
+
+// requestHeaders returns the request headers for the debug log with the values of not allow-listed headers redacted
+func (w *WebServer) requestHeaders(c *gin.Context) http.Header {
+	headers := make(http.Header, len(c.Request.Header))
+	for name, values := range c.Request.Header {
+		if w.loggedHeaderValues[name] && name != w.config.LogClientIPSecretHeader {
+			headers[name] = append([]string(nil), values...)
+			continue
+		}
+		headers[name] = []string{"[redacted]"}
+	}
+	return headers
+}
@@ -0,0 +1,78 @@
+package webserver
+
+import (
+	"github.com/rs/zerolog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebServer_SetAccessLogLevel(t *testing.T) {
+	var logs syncBuffer
+
+	logger := newTestLogger(&logs).Level(zerolog.InfoLevel)
+	webServer := newTestWebServer(t, WebServerConfig{Logger: &logger}, nil)
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	debugLines := func() int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer secret-token")
+		serve(webServer, req)
+		return strings.Count(logs.String(), "http request headers")
+	}
+
+	if n := debugLines(); n != 0 {
+		t.Fatalf("Debug lines are logged at info level: %v", n)
+	}
+
+	webServer.SetAccessLogLevel(zerolog.DebugLevel, time.Millisecond*100)
+	if n := debugLines(); n != 1 {
+		t.Fatalf("Debug lines aren't logged at debug level: %v", n)
+	}
+	if strings.Contains(logs.String(), "secret-token") {
+		t.Fatalf("Credentials are logged: %v", logs.String())
+	}
+
+	if !waitFor(time.Second, func() bool { return strings.Contains(logs.String(), "Access log level is reverted") }) {
+		t.Fatalf("Access log level isn't reverted after the duration")
+	}
+	if n := debugLines(); n != 1 {
+		t.Fatalf("Debug lines are logged after the level is reverted: %v", n)
+	}
+
+	webServer.SetAccessLogLevel(zerolog.DebugLevel, 0)
+	webServer.ResetAccessLogLevel()
+	if n := debugLines(); n != 1 {
+		t.Fatalf("Debug lines are logged after the level is reset: %v", n)
+	}
+}
+
+func TestWebServer_DebugRequestHeaders(t *testing.T) {
+	var logs syncBuffer
+
+	webServer := newTestWebServer(t, WebServerConfig{
+		LogClientIPSecretHeader: "X-CLIENT-IP-SECRET",
+		LogHeaderValues:         []string{"x-tenant"},
+	}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+	webServer.SetAccessLogLevel(zerolog.DebugLevel, 0)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Tenant", "tenant-1")
+	req.Header.Set("X-Api-Key", "api-key-value")
+	req.Header.Set("X-Client-Ip-Secret", "shared-secret")
+	serve(webServer, req)
+
+	out := logs.String()
+	if !strings.Contains(out, "test-agent") || !strings.Contains(out, "tenant-1") {
+		t.Fatalf("Allow-listed header values aren't logged: %v", out)
+	}
+	if strings.Contains(out, "api-key-value") || strings.Contains(out, "shared-secret") {
+		t.Fatalf("Not allow-listed header values are logged: %v", out)
+	}
+	if !strings.Contains(out, `"X-Api-Key":["[redacted]"]`) {
+		t.Fatalf("Redacted header isn't listed: %v", out)
+	}
+}
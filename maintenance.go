@@ -0,0 +1,93 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+var maintenanceDefaultBody = []byte("Service is temporarily unavailable due to maintenance")
+
+type pauseState struct {
+	sync.RWMutex
+	paused      bool
+	body        []byte
+	contentType string
+}
+
+// Pause makes the webserver serve the maintenance response to all but health requests until Resume is called.
+// MaintenanceFile is reloaded on every call, so it may be updated between the maintenance windows
+func (w *WebServer) Pause() {
+	body, contentType := w.maintenanceResponse()
+
+	w.pause.Lock()
+	w.pause.paused = true
+	w.pause.body = body
+	w.pause.contentType = contentType
+	w.pause.Unlock()
+
+	w.config.Logger.Info().Msg("webserver paused")
+}
+
+// Resume returns the paused webserver to the normal operation
+func (w *WebServer) Resume() {
+	w.pause.Lock()
+	w.pause.paused = false
+	w.pause.Unlock()
+
+	w.config.Logger.Info().Msg("webserver resumed")
+}
+
+// IsPaused reports whether the webserver is paused
+func (w *WebServer) IsPaused() bool {
+	w.pause.RLock()
+	defer w.pause.RUnlock()
+	return w.pause.paused
+}
+
+func (w *WebServer) maintenanceResponse() (body []byte, contentType string) {
+	body, contentType = w.config.MaintenanceBody, w.config.MaintenanceContentType
+
+	if w.config.MaintenanceFile != "" {
+		data, err := ioutil.ReadFile(w.config.MaintenanceFile)
+		if err != nil {
+			w.config.Logger.Error().Err(err).Msg("Can't read maintenance file")
+		} else {
+			body = data
+			if contentType == "" {
+				contentType = mime.TypeByExtension(filepath.Ext(w.config.MaintenanceFile))
+			}
+		}
+	}
+
+	if body == nil {
+		return maintenanceDefaultBody, "text/plain; charset=utf-8"
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	return
+}
+
+// pauseGate serves the maintenance response to all but health requests while the webserver is paused
+func (w *WebServer) pauseGate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w.pause.RLock()
+		paused, body, contentType := w.pause.paused, w.pause.body, w.pause.contentType
+		w.pause.RUnlock()
+
+		if !paused || w.isHealthPath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		if w.config.MaintenanceRetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(w.config.MaintenanceRetryAfter.Seconds())))
+		}
+		c.Data(w.config.MaintenanceStatus, contentType, body)
+		c.Abort()
+	}
+}
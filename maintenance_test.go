@@ -0,0 +1,82 @@
+package webserver
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebServer_PauseMaintenance(t *testing.T) {
+	var logs bytes.Buffer
+
+	dir, err := ioutil.TempDir("", "webserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "maintenance.html")
+	if err := ioutil.WriteFile(file, []byte("<html>first window</html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	webServer := newTestWebServer(t, WebServerConfig{
+		MaintenanceFile:       file,
+		MaintenanceRetryAfter: time.Minute,
+		LivenessPath:          "/healthz",
+	}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	webServer.Pause()
+
+	rec := serve(webServer, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "<html>first window</html>" {
+		t.Fatalf("Wrong maintenance response: %v %v", rec.Code, rec.Body.String())
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("Wrong maintenance content type: %v", rec.Header().Get("Content-Type"))
+	}
+	if rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("Wrong Retry-After: %v", rec.Header().Get("Retry-After"))
+	}
+	if rec = serve(webServer, httptest.NewRequest("GET", "/healthz", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("Liveness isn't available during pause: %v", rec.Code)
+	}
+
+	webServer.Resume()
+	if rec = serve(webServer, httptest.NewRequest("GET", "/", nil)); rec.Body.String() != "HELLO" {
+		t.Fatalf("Wrong answer after resume: %v", rec.Body.String())
+	}
+
+	//the file is reloaded on the next pause
+	if err := ioutil.WriteFile(file, []byte("<html>second window</html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	webServer.Pause()
+	if rec = serve(webServer, httptest.NewRequest("GET", "/", nil)); rec.Body.String() != "<html>second window</html>" {
+		t.Fatalf("Maintenance file isn't reloaded: %v", rec.Body.String())
+	}
+}
+
+func TestWebServer_PauseMaintenanceBody(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{
+		MaintenanceBody:        []byte(`{"error":"maintenance"}`),
+		MaintenanceContentType: "application/json",
+		MaintenanceStatus:      http.StatusTeapot,
+	}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+	webServer.Pause()
+
+	rec := serve(webServer, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusTeapot || rec.Body.String() != `{"error":"maintenance"}` ||
+		rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Wrong maintenance response: %v %v %v", rec.Code, rec.Header(), rec.Body.String())
+	}
+}
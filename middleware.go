@@ -0,0 +1,36 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"regexp"
+	"strings"
+)
+
+// UseWhen adds the middleware running only for the requests matching the predicate, see PathPrefix and PathMatch.
+// As gin's Use, it only applies to the routes registered after the call and isn't carried over by ReplaceServices
+func (w *WebServer) UseWhen(predicate func(*gin.Context) bool, middlewares ...gin.HandlerFunc) {
+	for _, mw := range middlewares {
+		mw := mw
+		w.gin.Use(func(c *gin.Context) {
+			if !predicate(c) {
+				c.Next()
+				return
+			}
+			mw(c)
+		})
+	}
+}
+
+// PathPrefix is the UseWhen predicate matching the request path prefix
+func PathPrefix(prefix string) func(*gin.Context) bool {
+	return func(c *gin.Context) bool {
+		return strings.HasPrefix(c.Request.URL.Path, prefix)
+	}
+}
+
+// PathMatch is the UseWhen predicate matching the request path with the regexp
+func PathMatch(re *regexp.Regexp) func(*gin.Context) bool {
+	return func(c *gin.Context) bool {
+		return re.MatchString(c.Request.URL.Path)
+	}
+}
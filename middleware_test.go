@@ -0,0 +1,48 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestWebServer_UseWhen(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{}, &bytes.Buffer{})
+	webServer.UseWhen(PathPrefix("/admin/"), func(c *gin.Context) {
+		c.Header("X-Admin", "1")
+		c.Next()
+	})
+	webServer.UseWhen(PathMatch(regexp.MustCompile(`/secret$`)), func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusForbidden)
+	})
+
+	handler := func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/admin/users", Method: "GET", Handler: handler},
+			{Path: "/admin/secret", Method: "GET", Handler: handler},
+			{Path: "/public/users", Method: "GET", Handler: handler},
+		},
+	})
+
+	tests := []struct {
+		path  string
+		code  int
+		admin string
+	}{
+		{"/admin/users", http.StatusOK, "1"},
+		{"/admin/secret", http.StatusForbidden, "1"},
+		{"/public/users", http.StatusOK, ""},
+	}
+	for _, test := range tests {
+		w := serve(webServer, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.code || w.Header().Get("X-Admin") != test.admin {
+			t.Fatalf("%v: wrong response: %v %q", test.path, w.Code, w.Header().Get("X-Admin"))
+		}
+	}
+}
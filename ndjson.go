@@ -0,0 +1,198 @@
+package webserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/rs/zerolog"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrAccessLogConflict is returned by NewWebServer if AccessLogNDJSON is combined with AsyncLogBuffer or AccessLogFile
+var ErrAccessLogConflict = errors.New("AccessLogNDJSON can't be combined with AsyncLogBuffer or AccessLogFile")
+
+// ndjsonEntry is the access log line of ndjsonAccessLog, the field names and types match the zerolog access log
+type ndjsonEntry struct {
+	Time             string   `json:"time"`
+	Level            string   `json:"level"`
+	Latency          int64    `json:"latency"`
+	ClientIP         string   `json:"clientIp"`
+	Path             string   `json:"path"`
+	Method           string   `json:"method"`
+	StatusCode       int      `json:"statusCode"`
+	BodySize         int      `json:"bodySize"`
+	RequestID        uint64   `json:"requestID"`
+	Route            string   `json:"route,omitempty"`
+	ServerName       string   `json:"serverName,omitempty"`
+	Cookies          []string `json:"cookies,omitempty"`
+	Trailers         []string `json:"trailers,omitempty"`
+	ContentEncoding  string   `json:"contentEncoding,omitempty"`
+	CompressionRatio float64  `json:"compressionRatio,omitempty"`
+	ClientAbort      bool     `json:"clientAbort,omitempty"`
+	WriteError       string   `json:"writeError,omitempty"`
+	TimedOut         bool     `json:"timedOut,omitempty"`
+	Message          string   `json:"message"`
+}
+
+// ndjsonHeadersEntry is the debug line with the request headers
+type ndjsonHeadersEntry struct {
+	Time      string      `json:"time"`
+	Level     string      `json:"level"`
+	RequestID uint64      `json:"requestID"`
+	Headers   http.Header `json:"headers"`
+	Message   string      `json:"message"`
+}
+
+// ndjsonAccessLog writes the access log as newline-delimited JSON to the writer in batches,
+// a batch is written by the background goroutine once it reaches the size or by the timer.
+// The lines are dropped (and counted) while the pending lines exceed maxBuffered bytes
+type ndjsonAccessLog struct {
+	mu          sync.Mutex
+	out         io.Writer
+	buf         bytes.Buffer
+	size        int
+	maxBuffered int
+	full        chan struct{}
+	stop        chan struct{}
+	done        chan struct{}
+	closed      bool
+	dropped     uint64
+}
+
+func newNDJSONAccessLog(out io.Writer, size, maxBuffered int, interval time.Duration) *ndjsonAccessLog {
+	l := &ndjsonAccessLog{
+		out:         out,
+		size:        size,
+		maxBuffered: maxBuffered,
+		full:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go l.run(interval)
+	return l
+}
+
+func (l *ndjsonAccessLog) run(interval time.Duration) {
+	defer close(l.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.writeBatch()
+		case <-l.full:
+			l.writeBatch()
+		case <-l.stop:
+			l.writeBatch()
+			return
+		}
+	}
+}
+
+// write buffers the lines of the entry if the level of the logger allows them
+func (l *ndjsonAccessLog) write(e accessLogEntry, logger *zerolog.Logger) {
+	if e.logger != nil {
+		logger = e.logger
+	}
+	level := zerolog.InfoLevel
+	if e.clientAbort || e.timedOut {
+		level = zerolog.WarnLevel
+	}
+	if !levelEnabled(logger, level) {
+		return
+	}
+
+	now := time.Now().Format(zerolog.TimeFieldFormat)
+	line, err := json.Marshal(ndjsonEntry{
+		Time:             now,
+		Level:            level.String(),
+		Latency:          latencyMillis(e.latency),
+		ClientIP:         e.clientIP,
+		Path:             e.path,
+		Method:           e.method,
+		StatusCode:       e.statusCode,
+		BodySize:         e.bodySize,
+		RequestID:        e.requestID,
+		Route:            e.route,
+		ServerName:       e.serverName,
+		Cookies:          e.cookies,
+		Trailers:         e.trailers,
+		ContentEncoding:  e.contentEncoding,
+		CompressionRatio: e.compressionRatio,
+		ClientAbort:      e.clientAbort,
+		WriteError:       e.writeErr,
+		TimedOut:         e.timedOut,
+		Message:          "http request",
+	})
+	if err != nil {
+		return
+	}
+	lines := [][]byte{line}
+
+	if e.headers != nil && levelEnabled(logger, zerolog.DebugLevel) {
+		if line, err = json.Marshal(ndjsonHeadersEntry{
+			Time:      now,
+			Level:     zerolog.DebugLevel.String(),
+			RequestID: e.requestID,
+			Headers:   e.headers,
+			Message:   "http request headers",
+		}); err == nil {
+			lines = append(lines, line)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range lines {
+		if l.closed || l.buf.Len() >= l.maxBuffered {
+			atomic.AddUint64(&l.dropped, 1)
+			continue
+		}
+		l.buf.Write(line)
+		l.buf.WriteByte('\n')
+	}
+	if l.buf.Len() >= l.size {
+		select {
+		case l.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// writeBatch swaps the buffer under the lock and writes the batch out of it, so the slow writer doesn't block the requests
+func (l *ndjsonAccessLog) writeBatch() {
+	l.mu.Lock()
+	if l.buf.Len() == 0 {
+		l.mu.Unlock()
+		return
+	}
+	batch := append([]byte(nil), l.buf.Bytes()...)
+	l.buf.Reset()
+	l.mu.Unlock()
+	_, _ = l.out.Write(batch)
+}
+
+// close stops the background goroutine and waits until it writes the buffered lines or the context is done,
+// the lines logged after close are dropped
+func (l *ndjsonAccessLog) close(ctx context.Context) {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.stop)
+	}
+	l.mu.Unlock()
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+	}
+}
+
+// levelEnabled reports whether the logger writes the lines of the level
+func levelEnabled(logger *zerolog.Logger, level zerolog.Level) bool {
+	return level >= logger.GetLevel() && level >= zerolog.GlobalLevel()
+}
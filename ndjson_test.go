@@ -0,0 +1,159 @@
+package webserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/rs/zerolog"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchWriter records the writes
+type batchWriter struct {
+	sync.Mutex
+	batches []string
+}
+
+func (w *batchWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	w.batches = append(w.batches, string(p))
+	return len(p), nil
+}
+
+func (w *batchWriter) get() []string {
+	w.Lock()
+	defer w.Unlock()
+	return append([]string(nil), w.batches...)
+}
+
+func TestWebServer_AccessLogNDJSON(t *testing.T) {
+	var out batchWriter
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{AccessLogNDJSON: &out, AccessLogFlushInterval: time.Hour}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	for i := 0; i < 5; i++ {
+		serve(webServer, httptest.NewRequest("GET", "/", nil))
+	}
+	if batches := out.get(); len(batches) != 0 {
+		t.Fatalf("Lines are written before the batch is full: %v", batches)
+	}
+	if bytes.Contains(logs.Bytes(), []byte("http request")) {
+		t.Fatalf("Access log is written to the zerolog logger too")
+	}
+
+	_ = webServer.Shutdown(context.Background())
+	batches := out.get()
+	if len(batches) != 1 {
+		t.Fatalf("Lines aren't flushed in one batch on shutdown: %v", len(batches))
+	}
+
+	scanner := bufio.NewScanner(bytes.NewBufferString(batches[0]))
+	lines := 0
+	for scanner.Scan() {
+		var entry struct {
+			Path       string `json:"path"`
+			StatusCode int    `json:"statusCode"`
+			RequestID  uint64 `json:"requestID"`
+			Message    string `json:"message"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		lines++
+		if entry.Path != "/" || entry.StatusCode != 200 || entry.RequestID != uint64(lines) || entry.Message != "http request" {
+			t.Fatalf("Wrong line: %q", scanner.Text())
+		}
+	}
+	if lines != 5 {
+		t.Fatalf("Wrong number of lines: %v", lines)
+	}
+}
+
+func TestWebServer_AccessLogNDJSONBatches(t *testing.T) {
+	var out batchWriter
+	lines := func() int {
+		return strings.Count(strings.Join(out.get(), ""), "\n")
+	}
+
+	webServer := newTestWebServer(t, WebServerConfig{AccessLogNDJSON: &out, AccessLogBatchSize: 1, AccessLogFlushInterval: time.Hour}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &PublicWebService{})
+	for i := 0; i < 3; i++ {
+		serve(webServer, httptest.NewRequest("GET", "/", nil))
+	}
+	if !waitFor(time.Second, func() bool { return lines() == 3 }) {
+		t.Fatalf("Lines aren't written once the batch size is reached: %v", lines())
+	}
+
+	webServer = newTestWebServer(t, WebServerConfig{AccessLogNDJSON: &out, AccessLogFlushInterval: time.Millisecond * 50}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &PublicWebService{})
+	serve(webServer, httptest.NewRequest("GET", "/", nil))
+	if !waitFor(time.Second, func() bool { return lines() == 4 }) {
+		t.Fatalf("Lines aren't written by the timer")
+	}
+	_ = webServer.Shutdown(context.Background())
+}
+
+func TestWebServer_AccessLogNDJSONLevel(t *testing.T) {
+	var out batchWriter
+
+	logger := newTestLogger(&bytes.Buffer{}).Level(zerolog.WarnLevel)
+	webServer := newTestWebServer(t, WebServerConfig{LoggerHttp: &logger, AccessLogNDJSON: &out}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	serve(webServer, httptest.NewRequest("GET", "/", nil))
+	webServer.SetAccessLogLevel(zerolog.DebugLevel, 0)
+	serve(webServer, httptest.NewRequest("GET", "/", nil))
+	_ = webServer.Shutdown(context.Background())
+
+	written := strings.Join(out.get(), "")
+	if strings.Count(written, `"message":"http request"`) != 1 || strings.Count(written, `"message":"http request headers"`) != 1 {
+		t.Fatalf("Logger level isn't respected: %v", written)
+	}
+	if !strings.Contains(written, `"requestID":2`) || strings.Contains(written, `"requestID":1,`) {
+		t.Fatalf("Wrong request is logged: %v", written)
+	}
+}
+
+func TestWebServer_AccessLogNDJSONSlowWriter(t *testing.T) {
+	sink := &blockingWriter{release: make(chan struct{})}
+
+	webServer := newTestWebServer(t, WebServerConfig{AccessLogNDJSON: sink, AccessLogBatchSize: 1, AccessLogMaxBuffered: 1}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	//the lines above the 64KB floor of AccessLogMaxBuffered are dropped
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			serve(webServer, httptest.NewRequest("GET", "/", nil))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatalf("Stalled writer blocks the requests")
+	}
+	if webServer.DroppedAccessLogs() == 0 {
+		t.Fatalf("Lines aren't dropped while the writer is stalled")
+	}
+	close(sink.release)
+	_ = webServer.Shutdown(context.Background())
+
+	for _, config := range []WebServerConfig{
+		{AccessLogNDJSON: sink, AsyncLogBuffer: 16},
+		{AccessLogNDJSON: sink, AccessLogFile: &LogFile{Path: "access.log"}},
+	} {
+		config.Logger = newTestLogger(&bytes.Buffer{})
+		if _, err := NewWebServer(config); err != ErrAccessLogConflict {
+			t.Fatalf("Conflicting access log config is accepted: %v", err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package webserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"html/template"
+	"net/http"
+)
+
+// OpenAPICacheMaxAge defines the Cache-Control max-age (in seconds) of the served OpenAPI spec
+var OpenAPICacheMaxAge = "3600"
+
+// SwaggerUIAssets defines the base url the Swagger UI assets are loaded from
+var SwaggerUIAssets = "https://unpkg.com/swagger-ui-dist@5"
+
+var swaggerUITemplate = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>API documentation</title>
+<link rel="stylesheet" href="{{.Assets}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.Assets}}/swagger-ui-bundle.js"></script>
+<script>window.ui = SwaggerUIBundle({url: "{{.SpecPath}}", dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`))
+
+// RegisterOpenAPI serves the provided OpenAPI spec (JSON or YAML) on the path
+// with the proper content type, CORS and caching headers
+func (w *WebServer) RegisterOpenAPI(path string, spec []byte) {
+	contentType := "application/yaml"
+	if trimmed := bytes.TrimSpace(spec); len(trimmed) > 0 && trimmed[0] == '{' {
+		contentType = "application/json"
+	}
+	sum := sha256.Sum256(spec)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	handler := func(c *gin.Context) {
+		c.Header("Access-Control-Allow-Origin", "*")
+		c.Header("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+		if c.Request.Method == http.MethodOptions {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		c.Header("Cache-Control", "public, max-age="+OpenAPICacheMaxAge)
+		c.Header("ETag", etag)
+		if c.GetHeader("If-None-Match") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		c.Data(http.StatusOK, contentType, spec)
+	}
+
+	w.gin.GET(path, handler)
+	w.gin.HEAD(path, handler)
+	w.gin.OPTIONS(path, handler)
+}
+
+// RegisterSwaggerUI serves the Swagger UI page on the path, the page renders the spec
+// served by RegisterOpenAPI on the specPath
+func (w *WebServer) RegisterSwaggerUI(path string, specPath string) {
+	var page bytes.Buffer
+	_ = swaggerUITemplate.Execute(&page, struct {
+		Assets   string
+		SpecPath string
+	}{SwaggerUIAssets, specPath})
+
+	w.gin.GET(path, func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+	})
+}
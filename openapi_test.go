@@ -0,0 +1,47 @@
+package webserver
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebServer_RegisterOpenAPI(t *testing.T) {
+	var logs bytes.Buffer
+	spec := []byte(`{"openapi": "3.0.0", "info": {"title": "test", "version": "1"}}`)
+
+	webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+	webServer.RegisterOpenAPI("/openapi.json", spec)
+	webServer.RegisterSwaggerUI("/docs", "/openapi.json")
+
+	rec := serve(webServer, httptest.NewRequest("GET", "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Wrong status code: %v", rec.Code)
+	}
+	if rec.Body.String() != string(spec) {
+		t.Fatalf("Wrong spec: %v", rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Wrong content type: %v", ct)
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("CORS header isn't set")
+	}
+
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("ETag header isn't set")
+	}
+	req := httptest.NewRequest("GET", "/openapi.json", nil)
+	req.Header.Set("If-None-Match", etag)
+	if rec = serve(webServer, req); rec.Code != http.StatusNotModified {
+		t.Fatalf("Wrong status code of conditional request: %v", rec.Code)
+	}
+
+	rec = serve(webServer, httptest.NewRequest("GET", "/docs", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "openapi.json") {
+		t.Fatalf("Wrong swagger ui page: %v %v", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,111 @@
+package webserver
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net"
+	"strings"
+)
+
+// parseTrustedProxies parses the list of IPs and CIDRs
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy IP: %v", proxy)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy CIDR: %w", err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// isTrustedProxy reports whether the request came from one of TrustedProxies
+func (w *WebServer) isTrustedProxy(c *gin.Context) bool {
+	return w.isTrustedIP(net.ParseIP(c.RemoteIP()))
+}
+
+// clientIP returns the client IP the server's own limits are keyed by: gin's ClientIP if TrustedProxies are set,
+// the peer address otherwise, as gin honors the client IP headers from any peer without TrustedProxies
+func (w *WebServer) clientIP(c *gin.Context) string {
+	if len(w.trustedProxies) > 0 {
+		return c.ClientIP()
+	}
+	return c.RemoteIP()
+}
+
+// isTrustedIP reports whether the IP belongs to one of TrustedProxies
+func (w *WebServer) isTrustedIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range w.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHeader returns the first value of the forwarded header if the request came from a trusted proxy
+func (w *WebServer) forwardedHeader(c *gin.Context, name string) string {
+	if !w.isTrustedProxy(c) {
+		return ""
+	}
+	return strings.TrimSpace(strings.Split(c.GetHeader(name), ",")[0])
+}
+
+// AbsoluteURL builds the absolute URL of the path on the host the request was sent to.
+// ForwardedProtoHeader (X-Forwarded-Proto by default) and X-Forwarded-Host are honored for the requests came from TrustedProxies,
+// the request's own scheme and host are used otherwise
+func AbsoluteURL(c *gin.Context, path string) string {
+	scheme, host := "http", c.Request.Host
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+
+	if v, ok := c.Get("webServer"); ok {
+		w := v.(*WebServer)
+		if proto := w.forwardedHeader(c, w.config.ForwardedProtoHeader); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if forwardedHost := w.forwardedHeader(c, "X-Forwarded-Host"); forwardedHost != "" {
+			host = forwardedHost
+		}
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return scheme + "://" + host + path
+}
+
+// loggedClientIP returns the client IP for the logs: LogClientIPHeader is honored only if the request
+// has LogClientIPSecretHeader matching LogClientIPSecret, so the clients can't spoof it; gin's ClientIP is used otherwise
+func (w *WebServer) loggedClientIP(c *gin.Context) string {
+	if w.config.LogClientIPHeader == "" || w.config.LogClientIPSecret == "" {
+		return c.ClientIP()
+	}
+	secret := c.GetHeader(w.config.LogClientIPSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(w.config.LogClientIPSecret)) != 1 {
+		return c.ClientIP()
+	}
+	ip := net.ParseIP(strings.TrimSpace(strings.Split(c.GetHeader(w.config.LogClientIPHeader), ",")[0]))
+	if ip == nil {
+		return c.ClientIP()
+	}
+	return ip.String()
+}
@@ -0,0 +1,93 @@
+package webserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAbsoluteURL(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{TrustedProxies: []string{"10.0.0.0/8"}}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/link", Method: "GET", Handler: func(c *gin.Context) {
+				c.String(200, AbsoluteURL(c, "next?page=2"))
+			}},
+		},
+	})
+
+	tests := []struct {
+		remoteAddr string
+		proto      string
+		host       string
+		expected   string
+	}{
+		//not behind a proxy
+		{"192.0.2.1:1234", "", "", "http://example.com/next?page=2"},
+		//behind a trusted proxy
+		{"10.1.2.3:1234", "https", "public.example.org", "https://public.example.org/next?page=2"},
+		{"10.1.2.3:1234", "https, http", "", "https://example.com/next?page=2"},
+		//behind a trusted proxy without the forwarded headers
+		{"10.1.2.3:1234", "", "", "http://example.com/next?page=2"},
+		//forwarded headers from an untrusted peer are spoofed
+		{"192.0.2.1:1234", "https", "evil.example.net", "http://example.com/next?page=2"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "http://example.com/link", nil)
+		req.RemoteAddr = test.remoteAddr
+		if test.proto != "" {
+			req.Header.Set("X-Forwarded-Proto", test.proto)
+		}
+		if test.host != "" {
+			req.Header.Set("X-Forwarded-Host", test.host)
+		}
+		if rec := serve(webServer, req); rec.Body.String() != test.expected {
+			t.Fatalf("Wrong absolute url for %+v: %v", test, rec.Body.String())
+		}
+	}
+}
+
+func TestWebServer_LoggedClientIP(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{
+		LogClientIPHeader: "X-Real-Client",
+		LogClientIPSecret: "s3cret",
+	}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{{Path: "/", Method: "GET", Handler: func(c *gin.Context) { c.Status(200) }}},
+	})
+
+	tests := []struct {
+		secret string
+		ip     string
+	}{
+		{"", "192.0.2.1"},
+		{"wrong", "192.0.2.1"},
+		{"s3cret", "203.0.113.7"},
+	}
+	for _, test := range tests {
+		logs.Reset()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Real-Client", "203.0.113.7")
+		if test.secret != "" {
+			req.Header.Set("X-Client-Ip-Secret", test.secret)
+		}
+		serve(webServer, req)
+
+		var entry struct {
+			ClientIP string `json:"clientIp"`
+		}
+		if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+			t.Fatalf("Can't decode access log %q: %v", logs.String(), err)
+		}
+		if entry.ClientIP != test.ip {
+			t.Fatalf("Secret %q: wrong logged client IP %v, expected %v", test.secret, entry.ClientIP, test.ip)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// countQueryParams counts the parameters of the raw query without decoding it, the empty ones ("a=1&&b=2") are skipped
+func countQueryParams(rawQuery string) int {
+	n := 0
+	for rawQuery != "" {
+		var param string
+		if i := strings.IndexByte(rawQuery, '&'); i >= 0 {
+			param, rawQuery = rawQuery[:i], rawQuery[i+1:]
+		} else {
+			param, rawQuery = rawQuery, ""
+		}
+		if param != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// queryParamsLimit rejects the requests having more than MaxQueryParams query parameters with 400
+// before the handlers parse the query
+func (w *WebServer) queryParamsLimit() gin.HandlerFunc {
+	max := w.config.MaxQueryParams
+	return func(c *gin.Context) {
+		if countQueryParams(c.Request.URL.RawQuery) > max {
+			w.Block(c, http.StatusBadRequest, "too many query parameters")
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package webserver
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebServer_MaxQueryParams(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{MaxQueryParams: 3}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	if w := serve(webServer, httptest.NewRequest("GET", "/?a=1&b=2&&c=3", nil)); w.Code != http.StatusOK {
+		t.Fatalf("Request within the limit is rejected: %v", w.Code)
+	}
+
+	flood := "/?" + strings.Repeat("p=1&", 1000)
+	if w := serve(webServer, httptest.NewRequest("GET", flood, nil)); w.Code != http.StatusBadRequest {
+		t.Fatalf("Request over the limit isn't rejected: %v", w.Code)
+	}
+	if !strings.Contains(logs.String(), "too many query parameters") {
+		t.Fatalf("Rejected request isn't logged as blocked")
+	}
+}
+
+func TestCountQueryParams(t *testing.T) {
+	tests := map[string]int{
+		"":            0,
+		"a=1":         1,
+		"a=1&b=2":     2,
+		"a=1&&b=2&":   2,
+		"a&b&c=%26&d": 4,
+	}
+	for query, expected := range tests {
+		if n := countQueryParams(query); n != expected {
+			t.Fatalf("%q: wrong count %v, expected %v", query, n, expected)
+		}
+	}
+}
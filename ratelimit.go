@@ -0,0 +1,93 @@
+package webserver
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit defines the token bucket: Rate requests per second on average with bursts up to Burst requests
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// ErrInvalidRateLimit is returned for the rate limits with non-positive Rate
+var ErrInvalidRateLimit = errors.New("invalid rate limit")
+
+func (limit RateLimit) validate() error {
+	if !(limit.Rate > 0) || math.IsInf(limit.Rate, 1) {
+		return fmt.Errorf("%w: rate %v", ErrInvalidRateLimit, limit.Rate)
+	}
+	return nil
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimits keeps a token bucket per key (client IP)
+type rateLimits struct {
+	sync.Mutex
+	limit     RateLimit
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// take takes a token from the bucket of the key, it returns the time to wait for the next token if there are none
+func (rl *rateLimits) take(key string, now time.Time) (bool, time.Duration) {
+	rl.Lock()
+	defer rl.Unlock()
+
+	//the buckets refilled completely are the same as the new ones, so they are dropped
+	refillTime := time.Duration(float64(rl.limit.Burst) / rl.limit.Rate * float64(time.Second))
+	if now.Sub(rl.lastSweep) > refillTime {
+		for k, b := range rl.buckets {
+			if now.Sub(b.last) > refillTime {
+				delete(rl.buckets, k)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(rl.limit.Burst), last: now}
+		rl.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(rl.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*rl.limit.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rl.limit.Rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// rateLimiter rejects the requests exceeding the limit per client IP (see clientIP) with 429 and Retry-After
+func (w *WebServer) rateLimiter(limit RateLimit) gin.HandlerFunc {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	limits := &rateLimits{
+		limit:   limit,
+		buckets: make(map[string]*tokenBucket),
+	}
+
+	return func(c *gin.Context) {
+		ok, wait := limits.take(w.clientIP(c), time.Now())
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			w.Block(c, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,126 @@
+package webserver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebServer_RouteRateLimit(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{RateLimit: &RateLimit{Rate: 100, Burst: 100}}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/login", Method: "POST", RateLimit: &RateLimit{Rate: 0.1, Burst: 3}, Handler: func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			}},
+			{Path: "/items", Method: "GET", Handler: func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			}},
+		},
+	})
+
+	for i := 0; i < 5; i++ {
+		login := serve(webServer, httptest.NewRequest("POST", "/login", nil))
+		items := serve(webServer, httptest.NewRequest("GET", "/items", nil))
+
+		if items.Code != http.StatusOK {
+			t.Fatalf("Lenient route is limited at request %v: %v", i, items.Code)
+		}
+		if i < 3 && login.Code != http.StatusOK {
+			t.Fatalf("Strict route is limited within the burst at request %v: %v", i, login.Code)
+		}
+		if i >= 3 {
+			if login.Code != http.StatusTooManyRequests {
+				t.Fatalf("Strict route isn't limited at request %v: %v", i, login.Code)
+			}
+			if retryAfter := login.Header().Get("Retry-After"); retryAfter == "" || retryAfter == "0" {
+				t.Fatalf("Wrong Retry-After: %q", retryAfter)
+			}
+		}
+	}
+
+	if !bytes.Contains(logs.Bytes(), []byte("rate limit exceeded")) {
+		t.Fatalf("Rate limited request isn't logged as blocked")
+	}
+}
+
+func TestRateLimits_Take(t *testing.T) {
+	limits := &rateLimits{limit: RateLimit{Rate: 10, Burst: 2}, buckets: make(map[string]*tokenBucket)}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limits.take("a", now); !ok {
+			t.Fatalf("Request within burst is limited")
+		}
+	}
+	ok, wait := limits.take("a", now)
+	if ok || wait != time.Millisecond*100 {
+		t.Fatalf("Wrong limiting of the exceeded request: %v %v", ok, wait)
+	}
+	if ok, _ = limits.take("b", now); !ok {
+		t.Fatalf("Another key shares the bucket")
+	}
+	if ok, _ = limits.take("a", now.Add(time.Millisecond*100)); !ok {
+		t.Fatalf("Bucket isn't refilled")
+	}
+}
+
+func TestWebServer_InvalidRateLimit(t *testing.T) {
+	var logs bytes.Buffer
+
+	for _, rate := range []float64{0, -1, math.NaN()} {
+		_, err := NewWebServer(WebServerConfig{Logger: newTestLogger(&logs), RateLimit: &RateLimit{Rate: rate, Burst: 1}})
+		if !errors.Is(err, ErrInvalidRateLimit) {
+			t.Fatalf("Global rate limit %v is accepted: %v", rate, err)
+		}
+
+		webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+		err = webServer.ServiceRegister("", &testWebService{
+			routes: []WebRoute{
+				{Path: "/limited", Method: "GET", Handler: func(c *gin.Context) {}, RateLimit: &RateLimit{Rate: rate}},
+			},
+		})
+		if !errors.Is(err, ErrInvalidRateLimit) {
+			t.Fatalf("Route rate limit %v is accepted: %v", rate, err)
+		}
+		if rec := serve(webServer, httptest.NewRequest("GET", "/limited", nil)); rec.Code != http.StatusNotFound {
+			t.Fatalf("Route with invalid rate limit is mounted: %v", rec.Code)
+		}
+	}
+}
+
+func TestWebServer_RateLimitForwardedFor(t *testing.T) {
+	for _, trusted := range []bool{false, true} {
+		config := WebServerConfig{}
+		if trusted {
+			config.TrustedProxies = []string{"192.0.2.0/24"}
+		}
+		webServer := newTestWebServer(t, config, &bytes.Buffer{})
+		webServer.ServiceRegister("", &testWebService{
+			routes: []WebRoute{{Path: "/login", Method: "POST", RateLimit: &RateLimit{Rate: 0.001, Burst: 1}, Handler: func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			}}},
+		})
+
+		limited := 0
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest("POST", "/login", nil)
+			req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i))
+			if serve(webServer, req).Code == http.StatusTooManyRequests {
+				limited++
+			}
+		}
+		//the rotating header bypasses the limit only if sent by a trusted proxy (httptest peer is 192.0.2.1)
+		if trusted && limited != 0 || !trusted && limited != 2 {
+			t.Fatalf("Wrong number of limited requests (trusted proxy: %v): %v", trusted, limited)
+		}
+	}
+}
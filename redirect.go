@@ -0,0 +1,54 @@
+package webserver
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// httpsRedirect redirects the plain http requests to https. The requests already forwarded as https
+// by a TLS terminating proxy are served as is to prevent a redirect loop, such a setup is likely
+// a misconfiguration (the redirector should be disabled behind the proxy), so it's logged once.
+// ForwardedProtoHeader is only honored for the requests came from TrustedProxies
+func (w *WebServer) httpsRedirect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil || w.isHealthPath(r.URL.Path) {
+			next.ServeHTTP(rw, r)
+			return
+		}
+
+		proto := ""
+		if remoteIP, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr)); err == nil && w.isTrustedIP(net.ParseIP(remoteIP)) {
+			proto = strings.TrimSpace(strings.Split(r.Header.Get(w.config.ForwardedProtoHeader), ",")[0])
+		}
+		if strings.EqualFold(proto, "https") {
+			if atomic.CompareAndSwapInt32(&w.httpsLoopLogged, 0, 1) {
+				w.config.Logger.Warn().
+					Str("header", w.config.ForwardedProtoHeader).
+					Str("clientIp", r.RemoteAddr).
+					Str("path", r.URL.Path).
+					Msg("Request is already forwarded as https, not redirecting to avoid a loop. The https redirect is likely misconfigured behind a TLS terminating proxy, further occurrences are not logged")
+			}
+			next.ServeHTTP(rw, r)
+			return
+		}
+
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if w.config.HTTPSRedirectPort != 0 && w.config.HTTPSRedirectPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(w.config.HTTPSRedirectPort))
+		} else if strings.Contains(host, ":") && !strings.HasPrefix(host, "[") {
+			host = "[" + host + "]"
+		}
+
+		status := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(rw, r, "https://"+host+r.URL.RequestURI(), status)
+	})
+}
@@ -0,0 +1,55 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebServer_HTTPSRedirect(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{
+		HTTPSRedirect:        true,
+		ForwardedProtoHeader: "x-forwarded-scheme",
+		TrustedProxies:       []string{"192.0.2.1"},
+	}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/test", Method: "GET", Handler: func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			}},
+		},
+	})
+
+	w := serve(webServer, httptest.NewRequest("GET", "http://example.com:8080/test?a=1", nil))
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "https://example.com/test?a=1" {
+		t.Fatalf("Wrong redirect: %v %v", w.Code, w.Header().Get("Location"))
+	}
+
+	w = serve(webServer, httptest.NewRequest("POST", "http://example.com/test", nil))
+	if w.Code != http.StatusPermanentRedirect {
+		t.Fatalf("Wrong redirect status of POST: %v", w.Code)
+	}
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "http://example.com/test", nil)
+		req.Header.Set("X-Forwarded-Scheme", "https")
+		if w = serve(webServer, req); w.Code != http.StatusOK {
+			t.Fatalf("Request forwarded as https is redirected: %v", w.Code)
+		}
+	}
+	if strings.Count(logs.String(), "not redirecting to avoid a loop") != 1 {
+		t.Fatalf("Redirect loop misconfiguration isn't logged once: %v", logs.String())
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com/test", nil)
+	req.RemoteAddr = "198.51.100.1:1234"
+	req.Header.Set("X-Forwarded-Scheme", "https")
+	if w = serve(webServer, req); w.Code != http.StatusMovedPermanently {
+		t.Fatalf("Forwarded scheme of an untrusted client bypasses the redirect: %v", w.Code)
+	}
+}
@@ -0,0 +1,57 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// routing is the routing table: gin engine with the global middlewares, the alt routes and the version aliases
+type routing struct {
+	gin       *gin.Engine
+	altRoutes []iRoute
+	versions  versionAliases
+}
+
+// newRouting creates the routing with the global middlewares and the health endpoints
+func (w *WebServer) newRouting() (*routing, error) {
+	rt := &routing{gin: gin.New()}
+	if len(w.config.TrustedProxies) > 0 {
+		if err := rt.gin.SetTrustedProxies(w.config.TrustedProxies); err != nil {
+			return nil, err
+		}
+	}
+	if len(w.config.ClientIPHeaders) > 0 {
+		rt.gin.RemoteIPHeaders = w.config.ClientIPHeaders
+	}
+	rt.gin.Use(w.middlewares...)
+	w.healthRegister(rt.gin)
+	rt.gin.NoRoute(func(c *gin.Context) {
+		w.altRouter(c, rt)
+	})
+	return rt, nil
+}
+
+// ReplaceServices atomically replaces the whole routing table with the services registered in the group,
+// the running server isn't restarted. The in-flight requests finish on the old routing, the new ones are routed
+// by the new one once it's built. The services are initialized with the new gin engine (Init), the replaced ones
+// aren't notified and must release their resources themselves. The global middlewares (and their state, e.g. rate limits)
+// are shared, but the routes and middlewares added outside ServiceRegister (UseWhen, RegisterOpenAPI, ServeFavicon...)
+// aren't carried over. The routing is left intact if the registration fails.
+// It must not be called concurrently with the other registration methods
+func (w *WebServer) ReplaceServices(group string, services ...WebService) error {
+	w.replaceMu.Lock()
+	defer w.replaceMu.Unlock()
+
+	rt, err := w.newRouting()
+	if err != nil {
+		return err
+	}
+	old := w.routing
+	w.routing = rt
+	if err := w.ServiceRegister(group, services...); err != nil {
+		w.routing = old
+		return err
+	}
+	w.served.Store(rt)
+	w.config.Logger.Info().Str("group", group).Int("services", len(services)).Msg("Web services are replaced")
+	return nil
+}
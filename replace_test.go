@@ -0,0 +1,102 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebServer_ReplaceServices(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	webServer := newTestWebServer(t, WebServerConfig{LivenessPath: "/healthz", EmptyServices: EmptyServiceError}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{{Path: "/old", Method: "GET", Handler: func(c *gin.Context) {
+			close(started)
+			<-release
+			c.String(http.StatusOK, "OLD")
+		}}},
+		altRoutes: []WebRoute{{Path: "^/alt/old$", Handler: func(c *gin.Context) { c.String(http.StatusOK, "ALT OLD") }}},
+	})
+
+	//the in-flight request must finish on the old routing
+	inFlight := make(chan *httptest.ResponseRecorder)
+	go func() {
+		inFlight <- serve(webServer, httptest.NewRequest("GET", "/old", nil))
+	}()
+	<-started
+
+	err := webServer.ReplaceServices("", &testWebService{
+		routes:    []WebRoute{{Path: "/new", Method: "GET", Handler: func(c *gin.Context) { c.String(http.StatusOK, "NEW") }}},
+		altRoutes: []WebRoute{{Path: "^/alt/new$", Handler: func(c *gin.Context) { c.String(http.StatusOK, "ALT NEW") }}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/new", http.StatusOK, "NEW"},
+		{"/alt/new", http.StatusOK, "ALT NEW"},
+		{"/old", http.StatusNotFound, ""},
+		{"/alt/old", http.StatusNotFound, ""},
+		{"/healthz", http.StatusOK, "OK"},
+	}
+	for _, test := range tests {
+		rec := serve(webServer, httptest.NewRequest("GET", test.path, nil))
+		if rec.Code != test.code || (test.body != "" && rec.Body.String() != test.body) {
+			t.Fatalf("%v: wrong response after replace: %v %q", test.path, rec.Code, rec.Body.String())
+		}
+	}
+
+	close(release)
+	if rec := <-inFlight; rec.Code != http.StatusOK || rec.Body.String() != "OLD" {
+		t.Fatalf("In-flight request isn't finished on the old routing: %v %q", rec.Code, rec.Body.String())
+	}
+
+	//the failed replacement keeps the routing
+	if err := webServer.ReplaceServices("", &testWebService{}); err == nil {
+		t.Fatalf("Empty services replacement doesn't fail")
+	}
+	if rec := serve(webServer, httptest.NewRequest("GET", "/new", nil)); rec.Body.String() != "NEW" {
+		t.Fatalf("Routing is changed by the failed replacement: %v %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestWebServer_ReplaceServicesRun(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{Port: 9103}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{{Path: "/old", Method: "GET", Handler: func(c *gin.Context) { c.String(http.StatusOK, "OLD") }}},
+	})
+	go webServer.Run()
+
+	get := func(path string) int {
+		resp, err := http.Get("http://localhost:9103" + path)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if !waitFor(time.Second*2, func() bool { return get("/old") == http.StatusOK }) {
+		t.Fatalf("Server isn't started")
+	}
+
+	//the server started by Run serves the replaced routing
+	err := webServer.ReplaceServices("", &testWebService{
+		routes: []WebRoute{{Path: "/new", Method: "GET", Handler: func(c *gin.Context) { c.String(http.StatusOK, "NEW") }}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := get("/new"); code != http.StatusOK {
+		t.Fatalf("Replaced route isn't served: %v", code)
+	}
+}
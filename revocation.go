@@ -0,0 +1,39 @@
+package webserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+)
+
+// ErrCertificateRevoked may be returned by ClientCertRevocationCheck for the revoked certificates
+var ErrCertificateRevoked = errors.New("certificate is revoked")
+
+// ErrRevocationCheckUnverified is returned by NewWebServer if the revocation check is set but the client certificates aren't verified
+var ErrRevocationCheckUnverified = errors.New("client certificate revocation check requires TLSConfig ClientAuth verifying the certificates")
+
+// revocationConfig returns the copy of the TLS config running the revocation check on the verified client
+// certificate chains after the config's own VerifyPeerCertificate. The handshake is rejected unless
+// at least one of the chains passes the check.
+// VerifyPeerCertificate isn't called on the resumed sessions, so the session tickets are disabled
+func revocationConfig(config *tls.Config, check func(chain []*x509.Certificate) error) *tls.Config {
+	config = config.Clone()
+	config.SessionTicketsDisabled = true
+
+	verify := config.VerifyPeerCertificate
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if verify != nil {
+			if err := verify(rawCerts, verifiedChains); err != nil {
+				return err
+			}
+		}
+		var err error
+		for _, chain := range verifiedChains {
+			if err = check(chain); err == nil {
+				return nil
+			}
+		}
+		return err
+	}
+	return config
+}
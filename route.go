@@ -0,0 +1,38 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// routeHandler wraps the route handler with the per-route features
+func (w *WebServer) routeHandler(route WebRoute) gin.HandlerFunc {
+	handler := gin.HandlerFunc(route.Handler)
+
+	if route.Isolated {
+		handler = w.isolatedHandler(handler, route.IsolationTimeout)
+	}
+
+	if route.RateLimit != nil {
+		next, limiter := handler, w.rateLimiter(*route.RateLimit)
+		handler = func(c *gin.Context) {
+			if limiter(c); c.IsAborted() {
+				return
+			}
+			next(c)
+		}
+	}
+
+	if route.Deprecated {
+		handler = w.deprecatedHandler(handler, route)
+	}
+
+	if route.ResponseContentType != "" {
+		next, contentType := handler, route.ResponseContentType
+		handler = func(c *gin.Context) {
+			c.Header("Content-Type", contentType)
+			next(c)
+		}
+	}
+
+	return handler
+}
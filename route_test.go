@@ -0,0 +1,42 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebServer_RouteResponseContentType(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/raw", Method: "GET", ResponseContentType: "application/json", Handler: func(c *gin.Context) {
+				_, _ = c.Writer.Write([]byte(`{"raw":true}`))
+			}},
+			{Path: "/override", Method: "GET", ResponseContentType: "application/json", Handler: func(c *gin.Context) {
+				c.Header("Content-Type", "text/csv")
+				_, _ = c.Writer.Write([]byte("a,b"))
+			}},
+		},
+		altRoutes: []WebRoute{
+			{Path: "^/alt", Method: "GET", ResponseContentType: "application/xml", Handler: func(c *gin.Context) {
+				_, _ = c.Writer.Write([]byte("<alt/>"))
+			}},
+		},
+	})
+
+	expected := map[string]string{
+		"/raw":      "application/json",
+		"/override": "text/csv",
+		"/alt":      "application/xml",
+	}
+	for path, contentType := range expected {
+		rec := serve(webServer, httptest.NewRequest("GET", path, nil))
+		if ct := rec.Header().Get("Content-Type"); ct != contentType {
+			t.Fatalf("Wrong content type for %v: %v", path, ct)
+		}
+	}
+}
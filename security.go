@@ -0,0 +1,33 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityEventBlocked is the event name of the log line emitted for every rejected request
+const SecurityEventBlocked = "blocked"
+
+// Block aborts the request with the status code and emits a structured security event with the reason.
+// Built-in protective middlewares reject requests through it, custom ones should do the same
+// to keep the stream of denied requests uniform
+func (w *WebServer) Block(c *gin.Context, status int, reason string) {
+	w.logBlocked(c, status, reason)
+	c.AbortWithStatus(status)
+}
+
+func (w *WebServer) logBlocked(c *gin.Context, status int, reason string) {
+	logger := w.config.SecurityLogger
+	if logger == nil {
+		logger = w.config.Logger
+	}
+
+	logger.Warn().
+		Str("event", SecurityEventBlocked).
+		Str("reason", reason).
+		Str("clientIp", w.loggedClientIP(c)).
+		Str("path", c.Request.URL.Path).
+		Str("method", c.Request.Method).
+		Int("statusCode", status).
+		Uint64("requestID", RequestID(c)).
+		Msg("request blocked")
+}
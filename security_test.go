@@ -0,0 +1,58 @@
+package webserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebServer_Block(t *testing.T) {
+	var logs, securityLogs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{SecurityLogger: newTestLogger(&securityLogs)}, &logs)
+
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/private", Method: "GET", Handler: func(c *gin.Context) { c.String(200, "SECRET") }},
+		},
+		middlewares: []func(ctx *gin.Context){
+			func(c *gin.Context) { webServer.Block(c, http.StatusForbidden, "denied by test") },
+		},
+	})
+
+	rec := serve(webServer, httptest.NewRequest("GET", "/private", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Wrong status code: %v", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "SECRET") {
+		t.Fatalf("Blocked request reached the handler")
+	}
+
+	var event map[string]interface{}
+	if err := json.Unmarshal(securityLogs.Bytes(), &event); err != nil {
+		t.Fatalf("Can't decode security log %q: %v", securityLogs.String(), err)
+	}
+	expected := map[string]interface{}{
+		"level":      "warn",
+		"event":      SecurityEventBlocked,
+		"reason":     "denied by test",
+		"path":       "/private",
+		"statusCode": float64(http.StatusForbidden),
+		"requestID":  float64(1),
+	}
+	for k, v := range expected {
+		if event[k] != v {
+			t.Fatalf("Wrong security log field %v: %v", k, event[k])
+		}
+	}
+	if _, ok := event["clientIp"]; !ok {
+		t.Fatalf("Security log doesn't have clientIp")
+	}
+	if strings.Contains(logs.String(), SecurityEventBlocked) {
+		t.Fatalf("Security event was written to the app logger")
+	}
+}
@@ -0,0 +1,40 @@
+package webserver
+
+import (
+	"crypto/tls"
+	"strings"
+)
+
+// sniConfig returns the copy of the TLS config selecting the certificate by the SNI server name from certs.
+// The names are matched case-insensitively, "*.example.com" keys match one label wildcards.
+// Not matched names fall back to the config's own GetCertificate or Certificates
+func sniConfig(config *tls.Config, certs map[string]*tls.Certificate) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config = config.Clone()
+
+	byName := make(map[string]*tls.Certificate, len(certs))
+	for name, cert := range certs {
+		byName[strings.ToLower(name)] = cert
+	}
+
+	fallback := config.GetCertificate
+	config.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+		if cert, ok := byName[name]; ok {
+			return cert, nil
+		}
+		if i := strings.Index(name, "."); i > 0 {
+			if cert, ok := byName["*"+name[i:]]; ok {
+				return cert, nil
+			}
+		}
+		if fallback != nil {
+			return fallback(hello)
+		}
+		//nil makes crypto/tls use Certificates
+		return nil, nil
+	}
+	return config
+}
@@ -0,0 +1,39 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const (
+	robotsTxtAllowAll    = "User-agent: *\nAllow: /\n"
+	robotsTxtDisallowAll = "User-agent: *\nDisallow: /\n"
+)
+
+// ServeFavicon serves the icon on /favicon.ico, the requests are excluded from the access log
+func (w *WebServer) ServeFavicon(icon []byte) {
+	contentType := http.DetectContentType(icon)
+	w.serveStatic("/favicon.ico", contentType, icon)
+}
+
+// ServeRobotsTxt serves the content on /robots.txt, the requests are excluded from the access log.
+// If the content is empty, the default one allows everything or disallows it if RobotsDisallowAll is set
+func (w *WebServer) ServeRobotsTxt(content string) {
+	if content == "" {
+		content = robotsTxtAllowAll
+		if w.config.RobotsDisallowAll {
+			content = robotsTxtDisallowAll
+		}
+	}
+	w.serveStatic("/robots.txt", "text/plain; charset=utf-8", []byte(content))
+}
+
+func (w *WebServer) serveStatic(path, contentType string, data []byte) {
+	w.noLoggingPaths[path] = true
+	handler := func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=86400")
+		c.Data(http.StatusOK, contentType, data)
+	}
+	w.gin.GET(path, handler)
+	w.gin.HEAD(path, handler)
+}
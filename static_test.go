@@ -0,0 +1,37 @@
+package webserver
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebServer_ServeFaviconAndRobotsTxt(t *testing.T) {
+	var logs bytes.Buffer
+	icon := []byte("\x00\x00\x01\x00\x01\x00\x10\x10\x00\x00\x01\x00\x20\x00")
+
+	webServer := newTestWebServer(t, WebServerConfig{RobotsDisallowAll: true}, &logs)
+	webServer.ServeFavicon(icon)
+	webServer.ServeRobotsTxt("")
+
+	rec := serve(webServer, httptest.NewRequest("GET", "/favicon.ico", nil))
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), icon) || rec.Header().Get("Content-Type") != "image/x-icon" {
+		t.Fatalf("Wrong favicon: %v %v", rec.Code, rec.Header())
+	}
+
+	rec = serve(webServer, httptest.NewRequest("GET", "/robots.txt", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != robotsTxtDisallowAll {
+		t.Fatalf("Wrong robots.txt: %v %v", rec.Code, rec.Body.String())
+	}
+
+	if logs.Len() != 0 {
+		t.Fatalf("Favicon and robots.txt requests are logged: %v", logs.String())
+	}
+
+	webServer = newTestWebServer(t, WebServerConfig{}, &logs)
+	webServer.ServeRobotsTxt("User-agent: *\nDisallow: /private\n")
+	if rec = serve(webServer, httptest.NewRequest("GET", "/robots.txt", nil)); rec.Body.String() != "User-agent: *\nDisallow: /private\n" {
+		t.Fatalf("Wrong custom robots.txt: %v", rec.Body.String())
+	}
+}
@@ -0,0 +1,27 @@
+package webserver
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// requestTimeout sets the deadline of the request context. Once the handlers returned after the deadline,
+// the request is marked as "timedOut" in the context and answered 504 if the response isn't written yet
+func (w *WebServer) requestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if ctx.Err() == context.DeadlineExceeded {
+			c.Set("timedOut", true)
+			if !c.Writer.Written() {
+				c.AbortWithStatus(http.StatusGatewayTimeout)
+			}
+		}
+	}
+}
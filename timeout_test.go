@@ -0,0 +1,58 @@
+package webserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebServer_RequestTimeout(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{RequestTimeout: time.Millisecond * 50}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/slow", Method: "GET", Handler: func(c *gin.Context) {
+				select {
+				case <-c.Request.Context().Done():
+				case <-time.After(time.Second):
+					c.String(http.StatusOK, "TOO LATE")
+				}
+			}},
+			{Path: "/fast", Method: "GET", Handler: func(c *gin.Context) { c.String(http.StatusOK, "FAST") }},
+		},
+	})
+
+	var entry struct {
+		Level      string `json:"level"`
+		StatusCode int    `json:"statusCode"`
+		TimedOut   bool   `json:"timedOut"`
+	}
+
+	rec := serve(webServer, httptest.NewRequest("GET", "/slow", nil))
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("Wrong status of timed out request: %v", rec.Code)
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Can't decode access log %q: %v", logs.String(), err)
+	}
+	if !entry.TimedOut || entry.StatusCode != http.StatusGatewayTimeout || entry.Level != "warn" {
+		t.Fatalf("Timed out request isn't marked in the access log: %v", logs.String())
+	}
+
+	logs.Reset()
+	entry.TimedOut = false
+	if rec = serve(webServer, httptest.NewRequest("GET", "/fast", nil)); rec.Body.String() != "FAST" {
+		t.Fatalf("Wrong answer: %v", rec.Body.String())
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Can't decode access log %q: %v", logs.String(), err)
+	}
+	if entry.TimedOut || entry.Level != "info" {
+		t.Fatalf("Normal request is marked as timed out: %v", logs.String())
+	}
+}
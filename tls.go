@@ -0,0 +1,129 @@
+package webserver
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+var errListenerClosed = errors.New("listener closed")
+
+// tlsListener wraps the listener with TLS if the webserver is configured to serve HTTPS
+func (w *WebServer) tlsListener(ln net.Listener) net.Listener {
+	if w.config.TLSConfig == nil {
+		return ln
+	}
+	if w.config.MaxConcurrentHandshakes <= 0 {
+		return tls.NewListener(ln, w.config.TLSConfig)
+	}
+	return newHandshakeListener(ln, w.config.TLSConfig, w.config.MaxConcurrentHandshakes,
+		w.config.HandshakeDropExcess, w.config.HandshakeTimeout)
+}
+
+// handshakeListener performs TLS handshakes of accepted connections in background limiting
+// the number of concurrent handshakes, Accept returns the connections with the handshake completed
+type handshakeListener struct {
+	net.Listener
+	config  *tls.Config
+	slots   chan struct{}
+	drop    bool
+	timeout time.Duration
+
+	conns     chan net.Conn
+	acceptErr chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newHandshakeListener(ln net.Listener, config *tls.Config, limit int, drop bool, timeout time.Duration) *handshakeListener {
+	l := &handshakeListener{
+		Listener:  ln,
+		config:    config,
+		slots:     make(chan struct{}, limit),
+		drop:      drop,
+		timeout:   timeout,
+		conns:     make(chan net.Conn),
+		acceptErr: make(chan error, 1),
+		done:      make(chan struct{}),
+	}
+	go l.acceptLoop()
+	return l
+}
+
+func (l *handshakeListener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(time.Millisecond * 5)
+				continue
+			}
+			l.acceptErr <- err
+			return
+		}
+		go l.handshake(conn)
+	}
+}
+
+func (l *handshakeListener) handshake(conn net.Conn) {
+	accepted := time.Now()
+	if l.drop {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			_ = conn.Close()
+			return
+		}
+	} else {
+		//the wait for a slot counts towards the handshake timeout, so a connection flood doesn't pile up
+		timer := time.NewTimer(l.timeout)
+		select {
+		case l.slots <- struct{}{}:
+			timer.Stop()
+		case <-timer.C:
+			_ = conn.Close()
+			return
+		case <-l.done:
+			timer.Stop()
+			_ = conn.Close()
+			return
+		}
+	}
+
+	tlsConn := tls.Server(conn, l.config)
+	_ = conn.SetDeadline(accepted.Add(l.timeout))
+	err := tlsConn.Handshake()
+	<-l.slots
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	select {
+	case l.conns <- tlsConn:
+	case <-l.done:
+		_ = tlsConn.Close()
+	}
+}
+
+func (l *handshakeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.acceptErr:
+		return nil, err
+	case <-l.done:
+		return nil, errListenerClosed
+	}
+}
+
+func (l *handshakeListener) Close() (err error) {
+	l.closeOnce.Do(func() {
+		close(l.done)
+		err = l.Listener.Close()
+	})
+	return
+}
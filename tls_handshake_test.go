@@ -0,0 +1,52 @@
+//go:build tlstest
+// +build tlstest
+
+package webserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+)
+
+// Run with: go test -tags tlstest -run Handshake
+func TestWebServer_MaxConcurrentHandshakes(t *testing.T) {
+	var logs bytes.Buffer
+	cert := newTestCertificate(t, "localhost")
+
+	webServer := newTestWebServer(t, WebServerConfig{
+		Port:                    9094,
+		TLSConfig:               &tls.Config{Certificates: []tls.Certificate{cert}},
+		MaxConcurrentHandshakes: 1,
+		HandshakeDropExcess:     true,
+	}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	if err := webServer.RunBg(); err != nil {
+		t.Fatal(err)
+	}
+	defer webServer.Shutdown(context.Background())
+
+	//the connection never sends ClientHello and holds the only handshake slot
+	stalled, err := net.Dial("tcp", "localhost:9094")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 100)
+
+	if _, err := newTestTLSClient(cert).Get("https://localhost:9094"); err == nil {
+		t.Fatalf("Excess handshake wasn't dropped")
+	}
+
+	stalled.Close()
+	time.Sleep(time.Millisecond * 100)
+
+	resp, err := newTestTLSClient(cert).Get("https://localhost:9094")
+	if err != nil {
+		t.Fatalf("Handshake failed after the slot was released: %v", err)
+	}
+	resp.Body.Close()
+}
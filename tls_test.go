@@ -0,0 +1,221 @@
+package webserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCertificate generates a self-signed certificate for the dns names
+func newTestCertificate(t *testing.T, dnsNames ...string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:              dnsNames,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// newTestTLSClient creates a client trusting the certificates
+func newTestTLSClient(certs ...tls.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert.Leaf)
+	}
+	return &http.Client{
+		Timeout: time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}
+}
+
+func TestWebServer_RunBgTLS(t *testing.T) {
+	var logs bytes.Buffer
+	cert := newTestCertificate(t, "localhost")
+
+	webServer := newTestWebServer(t, WebServerConfig{
+		Port:      9093,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+	}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	if err := webServer.RunBg(); err != nil {
+		t.Fatal(err)
+	}
+	defer webServer.Shutdown(context.Background())
+
+	resp, err := newTestTLSClient(cert).Get("https://localhost:9093")
+	if err != nil {
+		t.Fatalf("Failed get: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "HELLO" {
+		t.Fatalf("Wrong answer: %v", string(body))
+	}
+}
+
+func TestWebServer_SNICertificates(t *testing.T) {
+	var logs syncBuffer
+	defaultCert := newTestCertificate(t, "localhost")
+	certA := newTestCertificate(t, "a.test")
+	certB := newTestCertificate(t, "*.b.test")
+
+	webServer := newTestWebServer(t, WebServerConfig{
+		Port:      9099,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{defaultCert}},
+		SNICertificates: map[string]*tls.Certificate{
+			"A.test":   &certA,
+			"*.b.test": &certB,
+		},
+	}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	if err := webServer.RunBg(); err != nil {
+		t.Fatal(err)
+	}
+	defer webServer.Shutdown(context.Background())
+
+	tests := []struct {
+		serverName string
+		cert       tls.Certificate
+	}{
+		{"a.test", certA},
+		{"www.b.test", certB},
+		{"localhost", defaultCert},
+	}
+	for _, test := range tests {
+		client := newTestTLSClient(test.cert)
+		client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, "localhost:9099")
+		}
+		logs.Reset()
+		resp, err := client.Get("https://" + test.serverName + ":9099")
+		if err != nil {
+			t.Fatalf("%v: failed get: %v", test.serverName, err)
+		}
+		_, _ = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !bytes.Equal(resp.TLS.PeerCertificates[0].Raw, test.cert.Leaf.Raw) {
+			t.Fatalf("%v: wrong certificate %v", test.serverName, resp.TLS.PeerCertificates[0].DNSNames)
+		}
+		if !waitFor(time.Second, func() bool { return strings.Contains(logs.String(), `"serverName":"`+test.serverName+`"`) }) {
+			t.Fatalf("%v: server name isn't logged: %v", test.serverName, logs.String())
+		}
+	}
+}
+
+func TestWebServer_ClientCertRevocationCheck(t *testing.T) {
+	var logs bytes.Buffer
+	cert := newTestCertificate(t, "localhost")
+	revoked := newTestCertificate(t, "revoked.client")
+	valid := newTestCertificate(t, "valid.client")
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(revoked.Leaf)
+	clientCAs.AddCert(valid.Leaf)
+
+	webServer := newTestWebServer(t, WebServerConfig{
+		Port: 9100,
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+		},
+		ClientCertRevocationCheck: func(chain []*x509.Certificate) error {
+			if chain[0].SerialNumber.Cmp(revoked.Leaf.SerialNumber) == 0 {
+				return ErrCertificateRevoked
+			}
+			return nil
+		},
+	}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	if err := webServer.RunBg(); err != nil {
+		t.Fatal(err)
+	}
+	defer webServer.Shutdown(context.Background())
+
+	client := newTestTLSClient(cert)
+	client.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{valid}
+	resp, err := client.Get("https://localhost:9100")
+	if err != nil {
+		t.Fatalf("Valid client certificate is rejected: %s", err)
+	}
+	resp.Body.Close()
+
+	client = newTestTLSClient(cert)
+	client.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{revoked}
+	if resp, err = client.Get("https://localhost:9100"); err == nil {
+		resp.Body.Close()
+		t.Fatalf("Revoked client certificate is accepted")
+	}
+
+	check := func(chain []*x509.Certificate) error { return ErrCertificateRevoked }
+	for _, config := range []*tls.Config{nil, {ClientAuth: tls.RequestClientCert}, {ClientAuth: tls.RequireAnyClientCert}} {
+		_, err := NewWebServer(WebServerConfig{Logger: newTestLogger(&logs), TLSConfig: config, ClientCertRevocationCheck: check})
+		if err != ErrRevocationCheckUnverified {
+			t.Fatalf("Revocation check without the verified client certificates is accepted: %v", err)
+		}
+	}
+}
+
+func TestHandshakeListener_WaitTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := newHandshakeListener(ln, &tls.Config{}, 1, false, time.Millisecond*100)
+	defer l.Close()
+	//the only slot is busy
+	l.slots <- struct{}{}
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second * 2))
+	start := time.Now()
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatalf("Waiting connection isn't closed")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatalf("Waiting connection isn't closed by the handshake timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Wait for a handshake slot isn't bounded: %v", elapsed)
+	}
+}
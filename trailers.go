@@ -0,0 +1,39 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// declaredTrailers returns the trailer names declared by the "Trailer" response header
+func declaredTrailers(header http.Header) map[string]bool {
+	trailers := make(map[string]bool)
+	for _, values := range header["Trailer"] {
+		for _, name := range strings.Split(values, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				trailers[http.CanonicalHeaderKey(name)] = true
+			}
+		}
+	}
+	return trailers
+}
+
+// loggedTrailers returns the allow-listed response trailers, both declared and set with http.TrailerPrefix
+func (w *WebServer) loggedTrailers(c *gin.Context) (trailers []string) {
+	if len(w.config.LogTrailers) == 0 {
+		return
+	}
+	header := c.Writer.Header()
+	declared := declaredTrailers(header)
+	for _, name := range w.config.LogTrailers {
+		value := header.Get(http.TrailerPrefix + name)
+		if value == "" && declared[name] {
+			value = header.Get(name)
+		}
+		if value != "" {
+			trailers = append(trailers, name+"="+value)
+		}
+	}
+	return
+}
@@ -0,0 +1,58 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebServer_Trailers(t *testing.T) {
+	var logs bytes.Buffer
+
+	trailerHandler := func(c *gin.Context) {
+		c.Header("Trailer", "Grpc-Status")
+		c.String(http.StatusOK, "body")
+		c.Writer.Header().Set("Grpc-Status", "0")
+		c.Writer.Header().Set(http.TrailerPrefix+"Grpc-Message", "ok")
+	}
+	webServer := newTestWebServer(t, WebServerConfig{LogTrailers: []string{"grpc-status", "Grpc-Message"}}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/stream", Method: "GET", Handler: trailerHandler},
+			{Path: "/isolated", Method: "GET", Isolated: true, Handler: trailerHandler},
+		},
+	})
+
+	server := httptest.NewServer(webServer.Handler())
+	defer server.Close()
+
+	for _, path := range []string{"/stream", "/isolated"} {
+		logs.Reset()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request error: %v", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+
+		if string(body) != "body" {
+			t.Fatalf("%v: wrong body: %q", path, body)
+		}
+		if resp.Header.Get("Grpc-Status") != "" {
+			t.Fatalf("%v: trailer is sent as a header", path)
+		}
+		if resp.Trailer.Get("Grpc-Status") != "0" {
+			t.Fatalf("%v: declared trailer isn't sent: %v", path, resp.Trailer)
+		}
+		if path == "/stream" && resp.Trailer.Get("Grpc-Message") != "ok" {
+			t.Fatalf("%v: prefixed trailer isn't sent: %v", path, resp.Trailer)
+		}
+		if !strings.Contains(logs.String(), "Grpc-Status=0") {
+			t.Fatalf("%v: trailer isn't logged: %v", path, logs.String())
+		}
+	}
+}
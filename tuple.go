@@ -0,0 +1,28 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// TupleHandler adapts the handler returning the response status and body to gin.HandlerFunc.
+// The body is rendered as JSON (with Content-Length, see BufferedJSON), or XML if the client prefers it;
+// a nil body sends the status only.
+// Nothing is rendered if the handler has already written the response or aborted the request
+func TupleHandler(handler func(c *gin.Context) (int, interface{})) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, body := handler(c)
+		if c.Writer.Written() || c.IsAborted() {
+			return
+		}
+		if body == nil {
+			c.Status(status)
+			c.Writer.WriteHeaderNow()
+			return
+		}
+		if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEXML) == gin.MIMEXML {
+			c.XML(status, body)
+			return
+		}
+		BufferedJSON(c, status, body)
+	}
+}
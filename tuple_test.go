@@ -0,0 +1,33 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTupleHandler(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/items", Method: "POST", Handler: TupleHandler(func(c *gin.Context) (int, interface{}) {
+				return http.StatusCreated, gin.H{"id": 1}
+			})},
+			{Path: "/items", Method: "DELETE", Handler: TupleHandler(func(c *gin.Context) (int, interface{}) {
+				return http.StatusNoContent, nil
+			})},
+		},
+	})
+
+	w := serve(webServer, httptest.NewRequest("POST", "/items", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != `{"id":1}` || w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Fatalf("Wrong tuple response: %v %q %v", w.Code, w.Body.String(), w.Header().Get("Content-Type"))
+	}
+
+	w = serve(webServer, httptest.NewRequest("DELETE", "/items", nil))
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Fatalf("Wrong empty tuple response: %v %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,110 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// versionAliases holds the handlers of the versioned gin routes mounted under the version alias prefix
+type versionAliases struct {
+	latest string
+	// route ("METHOD /path") -> version -> handler
+	routes map[string]map[string]gin.HandlerFunc
+}
+
+// ServiceRegisterVersioned registers the services under the "/"+version prefix (e.g. "v2")
+// and mounts their gin routes under the VersionAlias prefix too. The alias always points
+// to the highest registered version regardless of the registration order,
+// the alias routes not provided by the highest version respond 404
+func (w *WebServer) ServiceRegisterVersioned(version string, services ...WebService) error {
+	//the alias shares the route handlers with the version, so the per-route state (e.g. rate limit) is common
+	var handlers []gin.HandlerFunc
+	if err := w.serviceRegister("/"+version, services, func(s WebService, route WebRoute, handler gin.HandlerFunc) {
+		handlers = append(handlers, handler)
+	}); err != nil {
+		return err
+	}
+
+	if w.versions.routes == nil {
+		w.versions.routes = make(map[string]map[string]gin.HandlerFunc)
+	}
+	if w.versions.latest == "" || compareVersions(version, w.versions.latest) > 0 {
+		w.versions.latest = version
+	}
+
+	for _, s := range services {
+		middlewares := s.Middlewares()
+		for _, route := range s.GinRoutes() {
+			key := route.Method + " " + route.Path
+			if _, ok := w.versions.routes[key]; !ok {
+				w.versions.routes[key] = make(map[string]gin.HandlerFunc)
+				w.gin.Handle(route.Method, joinPath("/"+w.config.VersionAlias, route.Path), versionAliasHandler(&w.versions, key))
+			}
+			handler := scopedErrorRenderer(s, handlers[0])
+			handlers = handlers[1:]
+			w.versions.routes[key][version] = func(c *gin.Context) {
+				for _, h := range middlewares {
+					h(c)
+					if c.IsAborted() {
+						return
+					}
+				}
+				handler(c)
+			}
+		}
+	}
+	return nil
+}
+
+func versionAliasHandler(versions *versionAliases, key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handler, ok := versions.routes[key][versions.latest]
+		if !ok {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		handler(c)
+	}
+}
+
+// compareVersions compares the versions like "v2" or "v1.10" numerically by segments
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var sa, sb string
+		if i < len(as) {
+			sa = as[i]
+		}
+		if i < len(bs) {
+			sb = bs[i]
+		}
+		na, errA := strconv.Atoi(sa)
+		nb, errB := strconv.Atoi(sb)
+		switch {
+		case errA == nil && errB == nil && na != nb:
+			if na > nb {
+				return 1
+			}
+			return -1
+		case (errA != nil || errB != nil) && sa != sb:
+			if sa > sb {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+func joinPath(prefix, path string) string {
+	if path == "" || path == "/" {
+		return prefix + "/"
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return prefix + path
+}
@@ -0,0 +1,81 @@
+package webserver
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newVersionedService(version string, paths ...string) WebService {
+	s := &testWebService{}
+	for _, path := range paths {
+		s.routes = append(s.routes, WebRoute{Path: path, Method: "GET", Handler: func(c *gin.Context) {
+			c.String(http.StatusOK, version+":"+c.Param("id"))
+		}})
+	}
+	return s
+}
+
+func TestWebServer_ServiceRegisterVersioned(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+	//the highest version is registered first to check the alias doesn't depend on the registration order
+	webServer.ServiceRegisterVersioned("v2", newVersionedService("v2", "/users/:id"))
+	webServer.ServiceRegisterVersioned("v1", newVersionedService("v1", "/users/:id", "/legacy/:id"))
+	webServer.ServiceRegisterVersioned("v1.5", newVersionedService("v1.5", "/users/:id"))
+
+	expected := map[string]string{
+		"/v1/users/1":     "v1:1",
+		"/v2/users/2":     "v2:2",
+		"/latest/users/3": "v2:3",
+		"/v1/legacy/4":    "v1:4",
+	}
+	for path, body := range expected {
+		rec := serve(webServer, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != body {
+			t.Fatalf("Wrong answer for %v: %v %v", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	if rec := serve(webServer, httptest.NewRequest("GET", "/latest/legacy/5", nil)); rec.Code != http.StatusNotFound {
+		t.Fatalf("Route missing in the latest version is served by alias: %v", rec.Code)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"v1", "v2", -1},
+		{"v10", "v9", 1},
+		{"v1.10", "v1.9", 1},
+		{"v2", "v2.0", -1},
+		{"v3", "v3", 0},
+	}
+	for _, test := range tests {
+		if r := compareVersions(test.a, test.b); r != test.expected {
+			t.Fatalf("Wrong comparison of %v and %v: %v", test.a, test.b, r)
+		}
+	}
+}
+
+func TestWebServer_ServiceRegisterVersionedRateLimit(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{}, &bytes.Buffer{})
+	webServer.ServiceRegisterVersioned("v2", &testWebService{
+		routes: []WebRoute{{Path: "/x", Method: "GET", RateLimit: &RateLimit{Rate: 0.001, Burst: 1}, Handler: func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		}}},
+	})
+
+	if rec := serve(webServer, httptest.NewRequest("GET", "/v2/x", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("Wrong status code: %v", rec.Code)
+	}
+	//the alias shares the limit with the version
+	if rec := serve(webServer, httptest.NewRequest("GET", "/latest/x", nil)); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Alias has its own rate limit: %v", rec.Code)
+	}
+}
@@ -2,13 +2,20 @@ package webserver
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
+	"io"
+	"net"
 	"net/http"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,8 +28,157 @@ var InitTimeout = time.Millisecond * 100
 type WebServerConfig struct {
 	Logger     *zerolog.Logger
 	LoggerHttp *zerolog.Logger
-	Addr       string
-	Port       int
+	// SecurityLogger receives the security events of rejected requests, Logger is used if nil
+	SecurityLogger *zerolog.Logger
+	Addr           string
+	Port           int
+	// MaxRequestBodySize limits the size of a request body, 0 means unlimited.
+	// The requests declaring a larger body are rejected with 413 (417 if they expect 100-continue)
+	MaxRequestBodySize int64
+	// CorrelationHeader is the request header carrying the correlation ID to outbound requests, X-Request-ID by default
+	CorrelationHeader string
+	// Warmup makes the webserver respond 503 with Retry-After to all but health requests
+	// until Ready is called or WarmupCheck passes
+	Warmup           bool
+	WarmupCheck      func() bool
+	WarmupRetryAfter time.Duration
+	// LivenessPath and ReadinessPath define the health endpoints, they are not registered if empty
+	LivenessPath  string
+	ReadinessPath string
+	// ReadinessCheckTimeout limits the duration of the readiness sub-checks (see AddReadinessCheck), 5s by default
+	ReadinessCheckTimeout time.Duration
+	// LogCookies is an allow-list of cookie names logged by the http logger when present on the request,
+	// cookie values are redacted unless LogCookieValues is set
+	LogCookies      []string
+	LogCookieValues bool
+	// LogTrailers is an allow-list of response trailer names logged by the http logger when sent
+	LogTrailers []string
+	// AsyncLogBuffer enables the asynchronous access logging through a buffer of the given size,
+	// the access log entries are dropped (and counted) instead of blocking requests when the buffer is full
+	AsyncLogBuffer int
+	// TLSConfig makes the server started with RunBg serve HTTPS
+	TLSConfig *tls.Config
+	// SNICertificates maps the SNI server names (or "*.domain" wildcards) to the certificates, the TLS config
+	// Certificates are the fallback. The negotiated server name is logged by the http logger as serverName
+	SNICertificates map[string]*tls.Certificate
+	// ClientCertRevocationCheck checks the revocation status (OCSP, CRL) of the client certificates on the TLS handshake,
+	// it's called with every verified chain (the leaf certificate first, then its issuer) and the handshake fails
+	// if none of the chains passes. It requires TLSConfig with ClientAuth verifying the client certificates
+	// (VerifyClientCertIfGiven or RequireAndVerifyClientCert), the session tickets are disabled so every handshake is checked
+	ClientCertRevocationCheck func(chain []*x509.Certificate) error
+	// MaxConcurrentHandshakes limits the number of in-progress TLS handshakes, 0 means unlimited.
+	// Excess handshakes wait for a free slot or are dropped if HandshakeDropExcess is set. HandshakeTimeout (10s by default)
+	// bounds both the wait and the handshake, the connection is closed when it expires
+	MaxConcurrentHandshakes int
+	HandshakeDropExcess     bool
+	HandshakeTimeout        time.Duration
+	// VersionAlias is the prefix the highest version registered with ServiceRegisterVersioned is available on,
+	// "latest" by default
+	VersionAlias string
+	// LogInstanceID adds the random instance ID generated at the webserver creation to all the log lines,
+	// it distinguishes the log lines of different instances and process restarts
+	LogInstanceID bool
+	// CookieSecret is the HMAC key of the signed cookies
+	CookieSecret []byte
+	// The maintenance response served while the webserver is paused, MaintenanceFile is reread on every Pause.
+	// The generic response is served if neither file nor body is defined
+	MaintenanceFile        string
+	MaintenanceBody        []byte
+	MaintenanceContentType string
+	MaintenanceStatus      int // 503 by default
+	MaintenanceRetryAfter  time.Duration
+	// AltRoutesDecodedPath makes the alt routes match the URL-decoded path (without query) instead of the raw RequestURI.
+	// Note an encoded slash (%2F) is decoded to "/" as well, so it can't be distinguished from the segments separator
+	AltRoutesDecodedPath bool
+	// LogAltRouteMisses logs the requests matched neither gin nor alt routes
+	LogAltRouteMisses bool
+	// TrustedProxies is a list of the proxy IPs or CIDRs the forwarded headers (client IP, scheme, host) are honored from.
+	// If it's empty gin trusts the client IP headers from any peer but AbsoluteURL ignores the forwarded headers,
+	// the rate limits and the idempotency keys use the peer address
+	TrustedProxies []string
+	// MaxLoggedPathLength caps the length of the path (with query) in the access log, 0 means unlimited.
+	// The truncated path is marked with the "...(truncated)" suffix, the route field isn't affected
+	MaxLoggedPathLength int
+	// RobotHeader is the request header marking the request as originated by a robot, X-Robot by default
+	RobotHeader string
+	// ClientIPHeaders are the headers the client IP is taken from (if sent by a trusted proxy), gin defaults are used if empty
+	ClientIPHeaders []string
+	// LogClientIPHeader overrides the client IP in the logs, it's honored only if the request has
+	// LogClientIPSecretHeader (X-Client-Ip-Secret by default) equal to LogClientIPSecret, e.g. set by the fronting proxy
+	LogClientIPHeader       string
+	LogClientIPSecretHeader string
+	LogClientIPSecret       string
+	// LogHeaderValues are the request headers whose values are logged in the debug request headers line
+	// in addition to the standard ones (Accept, Content-Type, User-Agent, ...), the other values are redacted
+	LogHeaderValues []string
+	// NoLoggingPaths are the paths (e.g. probes) excluded from the access log before the request is processed
+	NoLoggingPaths []string
+	// CaseInsensitiveRoutes lowercases the request path before routing, so /Users reaches the /users route
+	// (the gin routes must be lowercase). The whole path is lowercased, so the path parameters reach the handlers
+	// lowercase too (/users/Alice gives :name "alice"), the case-sensitive values (IDs, tokens) should go
+	// in the query or an alt route. If CaseInsensitiveRedirect is set, the client is redirected
+	// to the lowercase path instead. Alt routes match the original RequestURI unless AltRoutesDecodedPath is set,
+	// so their regexps should be case-insensitive, e.g. (?i)^/users/\d+$
+	CaseInsensitiveRoutes   bool
+	CaseInsensitiveRedirect bool
+	// RequestTimeout sets the deadline of the request context, the handlers should respect it.
+	// A request exceeded the deadline is answered 504 (if nothing is written yet) and logged as timed out
+	RequestTimeout time.Duration
+	// RobotsDisallowAll makes the default robots.txt served by ServeRobotsTxt disallow everything
+	RobotsDisallowAll bool
+	// RateLimit limits the requests rate per client IP globally, the routes may define their own limits in addition
+	RateLimit *RateLimit
+	// FaultInjection injects the latency and the errors for chaos testing, see FaultInjection
+	FaultInjection FaultInjection
+	// HTTPSRedirect redirects the plain http requests (except the health endpoints) to https on HTTPSRedirectPort (443 by default).
+	// The requests from TrustedProxies having ForwardedProtoHeader (X-Forwarded-Proto by default) equal to https are not redirected
+	HTTPSRedirect        bool
+	HTTPSRedirectPort    int
+	ForwardedProtoHeader string
+	// ErrorRenderer renders the errors passed to RenderError, the services may override it by implementing ErrorRendererService
+	ErrorRenderer ErrorRenderer
+	// ErrorRequestIDField is the field of the default error renderer body carrying the request ID, "requestID" by default
+	ErrorRequestIDField string
+	// ConnReadDeadline and ConnWriteDeadline set the absolute deadlines of the connection reads and writes
+	// refreshed when a request starts, so a stalled connection is closed regardless of the http.Server timeouts.
+	// The keep-alive connections waiting for the next request are closed after ConnReadDeadline too.
+	// They are only applied by RunBg
+	ConnReadDeadline  time.Duration
+	ConnWriteDeadline time.Duration
+	// Compression gzips the responses for the clients accepting it,
+	// the applied encoding and the compression ratio are logged by the http logger
+	Compression bool
+	// EmptyServices defines how the services registered without routes and middlewares are treated: warn (default), ignore or error.
+	// The services registering their routes in Init directly should use EmptyServiceIgnore
+	EmptyServices EmptyServicePolicy
+	// DecompressRequests decodes the gzip encoded request bodies, the requests with other encodings are
+	// rejected with 415 or passed undecoded depending on UnknownRequestEncoding
+	DecompressRequests     bool
+	UnknownRequestEncoding UnknownEncodingPolicy
+	// AccessLogFile makes the http logger write to the rotated (and optionally gzipped) file,
+	// LoggerHttp output is replaced (a new logger is created if it's nil). The file is closed by Shutdown and Close
+	AccessLogFile *LogFile
+	// AccessLogNDJSON makes the http logger write newline-delimited JSON lines to the writer instead of LoggerHttp,
+	// the lines are written in batches of AccessLogBatchSize bytes (64KB by default) or every AccessLogFlushInterval (1s by default).
+	// The batches are written by a background goroutine, the LoggerHttp level and SetAccessLogLevel apply to the lines.
+	// Shutdown waits for the buffered lines to be written. It can't be combined with AsyncLogBuffer or AccessLogFile
+	AccessLogNDJSON        io.Writer
+	AccessLogBatchSize     int
+	AccessLogFlushInterval time.Duration
+	// AccessLogMaxBuffered limits the NDJSON lines pending while the writer is slow, the lines above it are dropped
+	// and counted by DroppedAccessLogs. It's 4MB by default and not less than 64KB and AccessLogBatchSize
+	AccessLogMaxBuffered int
+	// MaxAltRoutesPerService limits the number of the alt routes (regexps matched one by one by AltRouter) a service may register,
+	// 0 means unlimited
+	MaxAltRoutesPerService int
+	// MaxQueryParams rejects the requests having more query parameters with 400, 0 means unlimited
+	MaxQueryParams int
+	// StripResponseHeaders are removed from the responses right before the headers are sent (e.g. Server, X-Powered-By)
+	StripResponseHeaders []string
+	// MaxConcurrentRequests limits the number of the requests processed at once, 0 means unlimited.
+	// The excess requests wait up to ConcurrencyQueueTimeout for a slot and are answered 503 if it's not freed
+	MaxConcurrentRequests   int
+	ConcurrencyQueueTimeout time.Duration
 }
 
 type globalState struct {
@@ -31,50 +187,311 @@ type globalState struct {
 }
 
 type WebServer struct {
-	config    WebServerConfig
-	gin       *gin.Engine
-	altRoutes []iRoute
+	config     WebServerConfig
+	instanceID string
+	*routing
 	state     globalState
 	srv       *http.Server // is only used in gorouting startup mode
+	running   int32
+	ready     int32
+	accessLog *asyncAccessLog
+	conns     int64
+	queued    int64
+	pause     pauseState
+
+	afterResponse  afterResponseHooks
+	lifecycle      *lifecycle
+	noLoggingPaths map[string]bool
+	latency        latencyStats
+
+	readinessChecks readinessChecks
+	accessLogFile   *rotatingFile
+	accessLogLevel  logLevelOverride
+	ndjsonLog       *ndjsonAccessLog
+
+	trustedProxies []*net.IPNet
+	// loggedHeaderValues is the allow-list of the request headers logged with the values at debug level
+	loggedHeaderValues map[string]bool
+
+	httpsLoopLogged int32
+
+	// middlewares are the global middlewares shared by the routings, see ReplaceServices
+	middlewares []gin.HandlerFunc
+	served      atomic.Value
+	replaceMu   sync.Mutex
 }
 
+// iRoute is the alt route pattern with the handlers by method, "" method handler serves any method
 type iRoute struct {
-	Path    *regexp.Regexp
-	Method  string
-	Handler func(ctx *gin.Context)
+	Path     *regexp.Regexp
+	Handlers map[string]func(ctx *gin.Context)
+	Methods  []string
 }
 
 func NewWebServer(config WebServerConfig) (*WebServer, error) {
 
+	if config.CorrelationHeader == "" {
+		config.CorrelationHeader = "X-Request-ID"
+	}
+	if config.RobotHeader == "" {
+		config.RobotHeader = "X-Robot"
+	}
+	if config.LogClientIPSecretHeader == "" {
+		config.LogClientIPSecretHeader = "X-Client-Ip-Secret"
+	}
+	if config.ErrorRequestIDField == "" {
+		config.ErrorRequestIDField = "requestID"
+	}
+	if config.ForwardedProtoHeader == "" {
+		config.ForwardedProtoHeader = "X-Forwarded-Proto"
+	}
+	//header names are canonicalized to match the keys of the parsed request headers
+	config.CorrelationHeader = http.CanonicalHeaderKey(config.CorrelationHeader)
+	config.RobotHeader = http.CanonicalHeaderKey(config.RobotHeader)
+	config.ForwardedProtoHeader = http.CanonicalHeaderKey(config.ForwardedProtoHeader)
+	config.LogClientIPSecretHeader = http.CanonicalHeaderKey(config.LogClientIPSecretHeader)
+	clientIPHeaders := make([]string, 0, len(config.ClientIPHeaders))
+	for _, h := range config.ClientIPHeaders {
+		clientIPHeaders = append(clientIPHeaders, http.CanonicalHeaderKey(h))
+	}
+	config.ClientIPHeaders = clientIPHeaders
+	logTrailers := make([]string, 0, len(config.LogTrailers))
+	for _, h := range config.LogTrailers {
+		logTrailers = append(logTrailers, http.CanonicalHeaderKey(h))
+	}
+	config.LogTrailers = logTrailers
+	stripResponseHeaders := make([]string, 0, len(config.StripResponseHeaders))
+	for _, h := range config.StripResponseHeaders {
+		stripResponseHeaders = append(stripResponseHeaders, http.CanonicalHeaderKey(h))
+	}
+	config.StripResponseHeaders = stripResponseHeaders
+	if config.WarmupRetryAfter == 0 {
+		config.WarmupRetryAfter = time.Second
+	}
+	if config.AccessLogBatchSize == 0 {
+		config.AccessLogBatchSize = 64 << 10
+	}
+	if config.AccessLogMaxBuffered == 0 {
+		config.AccessLogMaxBuffered = 4 << 20
+	}
+	if config.AccessLogMaxBuffered < 64<<10 {
+		config.AccessLogMaxBuffered = 64 << 10
+	}
+	if config.AccessLogMaxBuffered < config.AccessLogBatchSize {
+		config.AccessLogMaxBuffered = config.AccessLogBatchSize
+	}
+	if config.AccessLogFlushInterval == 0 {
+		config.AccessLogFlushInterval = time.Second
+	}
+	if config.ReadinessCheckTimeout == 0 {
+		config.ReadinessCheckTimeout = time.Second * 5
+	}
+	if len(config.SNICertificates) > 0 {
+		config.TLSConfig = sniConfig(config.TLSConfig, config.SNICertificates)
+	}
+	if config.ClientCertRevocationCheck != nil {
+		if config.TLSConfig == nil || config.TLSConfig.ClientAuth != tls.VerifyClientCertIfGiven &&
+			config.TLSConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+			return nil, ErrRevocationCheckUnverified
+		}
+		config.TLSConfig = revocationConfig(config.TLSConfig, config.ClientCertRevocationCheck)
+	}
+	if config.HandshakeTimeout == 0 {
+		config.HandshakeTimeout = time.Second * 10
+	}
+	if config.MaintenanceStatus == 0 {
+		config.MaintenanceStatus = http.StatusServiceUnavailable
+	}
+	if config.VersionAlias == "" {
+		config.VersionAlias = "latest"
+	}
+
+	if config.RateLimit != nil {
+		if err := config.RateLimit.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if config.AccessLogNDJSON != nil && (config.AsyncLogBuffer > 0 || config.AccessLogFile != nil) {
+		return nil, ErrAccessLogConflict
+	}
+	var accessLogFile *rotatingFile
+	if config.AccessLogFile != nil {
+		var err error
+		if accessLogFile, err = newRotatingFile(*config.AccessLogFile, config.Logger); err != nil {
+			return nil, fmt.Errorf("can't open access log file: %w", err)
+		}
+		var logger zerolog.Logger
+		if config.LoggerHttp != nil {
+			logger = config.LoggerHttp.Output(accessLogFile)
+		} else {
+			logger = zerolog.New(accessLogFile).With().Timestamp().Logger()
+		}
+		config.LoggerHttp = &logger
+	}
+
+	instanceID, err := newInstanceID()
+	if err != nil {
+		return nil, fmt.Errorf("can't generate instance ID: %w", err)
+	}
+	if config.LogInstanceID {
+		config.Logger = withInstanceID(config.Logger, instanceID)
+		config.LoggerHttp = withInstanceID(config.LoggerHttp, instanceID)
+		config.SecurityLogger = withInstanceID(config.SecurityLogger, instanceID)
+	}
+
+	if config.FaultInjection.Enabled {
+		guard := config.FaultInjection.Guard
+		if guard == nil {
+			guard = defaultFaultInjectionGuard
+		}
+		if !guard() {
+			return nil, ErrFaultInjectionNotAllowed
+		}
+	}
+
+	trustedProxies, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
 	gin.SetMode(gin.ReleaseMode)
 	webServer := &WebServer{
-		config: config,
-		gin:    gin.New(),
+		config:         config,
+		instanceID:     instanceID,
+		accessLogFile:  accessLogFile,
+		trustedProxies: trustedProxies,
+		lifecycle:      newLifecycle(),
 		state: globalState{
 			requestCounter: 0,
 		},
 	}
 
-	webServer.gin.Use(
+	webServer.noLoggingPaths = make(map[string]bool, len(config.NoLoggingPaths))
+	for _, path := range config.NoLoggingPaths {
+		webServer.noLoggingPaths[path] = true
+	}
+	webServer.loggedHeaderValues = make(map[string]bool, len(loggedHeaderValues)+len(config.LogHeaderValues))
+	for _, name := range append(loggedHeaderValues, config.LogHeaderValues...) {
+		webServer.loggedHeaderValues[http.CanonicalHeaderKey(name)] = true
+	}
+
+	webServer.use(
 		func(c *gin.Context) {
 			webServer.state.Lock()
 			webServer.state.requestCounter++
 			//set requestID
 			c.Set("requestID", webServer.state.requestCounter)
 			webServer.state.Unlock()
+			c.Set("webServer", webServer)
 			c.Next()
 		},
 	)
 
-	webServer.gin.Use(webServer.httpLogger(config.LoggerHttp))
-	webServer.gin.Use(webServer.robotsDetect(robotsUserAgent))
-	webServer.gin.Use(gin.Recovery())
+	if config.AccessLogNDJSON != nil {
+		webServer.ndjsonLog = newNDJSONAccessLog(config.AccessLogNDJSON, config.AccessLogBatchSize,
+			config.AccessLogMaxBuffered, config.AccessLogFlushInterval)
+	} else if config.AsyncLogBuffer > 0 {
+		webServer.accessLog = newAsyncAccessLog(config.LoggerHttp, config.AsyncLogBuffer)
+	}
+	webServer.use(webServer.httpLogger(config.LoggerHttp))
+	webServer.use(webServer.robotsDetect(robotsUserAgent))
+	webServer.use(gin.Recovery())
+	if config.Compression {
+		webServer.use(webServer.compression())
+	}
 
-	webServer.gin.NoRoute(webServer.AltRouter)
+	if !config.Warmup {
+		webServer.ready = 1
+	}
+	webServer.use(webServer.warmupGate())
+	webServer.use(webServer.pauseGate())
+	if config.MaxQueryParams > 0 {
+		webServer.use(webServer.queryParamsLimit())
+	}
+	if config.DecompressRequests {
+		webServer.use(webServer.requestDecompression())
+	}
+	if config.MaxRequestBodySize > 0 {
+		webServer.use(webServer.bodyLimit())
+	}
+	if config.FaultInjection.Enabled {
+		config.Logger.Warn().Float64("probability", config.FaultInjection.Probability).Int("statusCode", config.FaultInjection.Status).Msg("Fault injection is enabled")
+		webServer.use(webServer.faultInjector(config.FaultInjection))
+	}
+	if config.RateLimit != nil {
+		webServer.use(webServer.rateLimiter(*config.RateLimit))
+	}
+	if config.MaxConcurrentRequests > 0 {
+		webServer.use(webServer.concurrencyLimiter())
+	}
+	if config.RequestTimeout > 0 {
+		webServer.use(webServer.requestTimeout(config.RequestTimeout))
+	}
+	if webServer.routing, err = webServer.newRouting(); err != nil {
+		return nil, err
+	}
+	webServer.served.Store(webServer.routing)
 	return webServer, nil
 }
 
-func (w *WebServer) ServiceRegister(group string, services ...WebService) {
+// use adds the global middlewares
+func (w *WebServer) use(middlewares ...gin.HandlerFunc) {
+	w.middlewares = append(w.middlewares, middlewares...)
+}
+
+// EmptyServicePolicy defines how ServiceRegister treats the services having no routes and no middlewares
+type EmptyServicePolicy int
+
+const (
+	EmptyServiceWarn EmptyServicePolicy = iota
+	EmptyServiceIgnore
+	EmptyServiceError
+)
+
+var ErrEmptyService = errors.New("web service has no routes and no middlewares")
+var ErrTooManyAltRoutes = errors.New("web service has too many alt routes")
+
+// ServiceRegister registers the services routes and middlewares in the group.
+// The services are initialized first, the Init errors are logged. It fails before registering anything
+// if a service is empty and EmptyServices is EmptyServiceError, a service has more than MaxAltRoutesPerService
+// alt routes or an invalid route rate limit
+func (w *WebServer) ServiceRegister(group string, services ...WebService) error {
+	return w.serviceRegister(group, services, nil)
+}
+
+// serviceRegister registers the services, onRoute (if set) gets every gin route with the handler
+// wrapped with the per-route features, so it can be mounted elsewhere sharing the per-route state (e.g. rate limit)
+func (w *WebServer) serviceRegister(group string, services []WebService, onRoute func(s WebService, route WebRoute, handler gin.HandlerFunc)) error {
+	//some service related initalization, the services may build their routes there, so it goes before the checks
+	for _, s := range services {
+		if err := s.Init(w.gin); err != nil {
+			w.config.Logger.Error().Err(err).Msg("Can't initialize web service")
+		}
+	}
+
+	for _, s := range services {
+		if max := w.config.MaxAltRoutesPerService; max > 0 && len(s.AltRoutes()) > max {
+			return fmt.Errorf("%w: %T in group %q has %v alt routes, max %v", ErrTooManyAltRoutes, s, group, len(s.AltRoutes()), max)
+		}
+		for _, route := range append(append([]WebRoute(nil), s.GinRoutes()...), s.AltRoutes()...) {
+			if route.RateLimit == nil {
+				continue
+			}
+			if err := route.RateLimit.validate(); err != nil {
+				return fmt.Errorf("%w of route %v %v of %T in group %q", err, route.Method, route.Path, s, group)
+			}
+		}
+		if len(s.GinRoutes()) > 0 || len(s.AltRoutes()) > 0 || len(s.Middlewares()) > 0 {
+			continue
+		}
+		switch w.config.EmptyServices {
+		case EmptyServiceError:
+			return fmt.Errorf("%w: %T in group %q", ErrEmptyService, s, group)
+		case EmptyServiceWarn:
+			w.config.Logger.Warn().Str("group", group).Str("service", fmt.Sprintf("%T", s)).Msg("Web service has no routes and no middlewares, is it wired correctly?")
+		}
+	}
+
 	var router *gin.RouterGroup
 	//create group if defined
 	if group != "" {
@@ -84,64 +501,131 @@ func (w *WebServer) ServiceRegister(group string, services ...WebService) {
 	}
 
 	for _, s := range services {
-		//some service related initalization
-		if err := s.Init(w.gin); err != nil {
-			w.config.Logger.Error().Err(err).Msg("Can't initialize web service")
-		}
 		//register service middlewares
 		for _, h := range s.Middlewares() {
 			router.Use(h)
 		}
 		//register service's handlers
 		for _, route := range s.GinRoutes() {
-			router.Handle(route.Method, route.Path, route.Handler)
+			handler := w.routeHandler(route)
+			router.Handle(route.Method, route.Path, scopedErrorRenderer(s, handler))
+			if onRoute != nil {
+				onRoute(s, route, handler)
+			}
 		}
 
 		//register service's alternative routes described with regexp (regexp isn't supported by gin)
+		middlewares := s.Middlewares()
 		for _, route := range s.AltRoutes() {
-			w.altRoutes = append(
-				w.altRoutes,
-				iRoute{
-					regexp.MustCompile(route.Path),
-					route.Method,
-					func(c *gin.Context) {
-						for _, h := range s.Middlewares() {
-							h(c)
-						}
-						route.Handler(c)
-					},
-				})
+			handler := scopedErrorRenderer(s, w.routeHandler(route))
+			w.addAltRoute(route.Path, route.Method, func(c *gin.Context) {
+				for _, h := range middlewares {
+					h(c)
+				}
+				handler(c)
+			})
+		}
+	}
+	return nil
+}
+
+// ServiceRegisterIf registers the services only if enabled (e.g. by a feature flag evaluated at startup),
+// the routes of disabled services are not mounted at all
+func (w *WebServer) ServiceRegisterIf(enabled bool, group string, services ...WebService) error {
+	if !enabled {
+		w.config.Logger.Info().Str("group", group).Int("services", len(services)).Msg("Web services are disabled, skip registration")
+		return nil
+	}
+	return w.ServiceRegister(group, services...)
+}
+
+// addAltRoute adds the handler to the alt route of the pattern, the routes of the same pattern
+// are grouped to dispatch by method after a single regexp match. The first handler of the method wins
+func (w *WebServer) addAltRoute(pattern string, method string, handler func(c *gin.Context)) {
+	method = strings.ToUpper(method)
+	for i := range w.altRoutes {
+		route := &w.altRoutes[i]
+		if route.Path.String() != pattern {
+			continue
+		}
+		if _, ok := route.Handlers[method]; !ok {
+			route.Handlers[method] = handler
+			route.Methods = append(route.Methods, method)
 		}
+		return
 	}
+	w.altRoutes = append(w.altRoutes, iRoute{
+		Path:     regexp.MustCompile(pattern),
+		Handlers: map[string]func(ctx *gin.Context){method: handler},
+		Methods:  []string{method},
+	})
 }
 
+// AltRouter matches the request with the alt routes in the registration order,
+// the matched route responds 405 if it has no handler for the request method
 func (w *WebServer) AltRouter(c *gin.Context) {
-	for _, route := range w.altRoutes {
-		if route.Path.MatchString(c.Request.RequestURI) {
-			route.Handler(c)
+	w.altRouter(c, w.served.Load().(*routing))
+}
+
+func (w *WebServer) altRouter(c *gin.Context, rt *routing) {
+	path := c.Request.RequestURI
+	if w.config.AltRoutesDecodedPath {
+		path = c.Request.URL.Path
+	}
+	for _, route := range rt.altRoutes {
+		if route.Path.MatchString(path) {
+			if handler, ok := route.Handlers[c.Request.Method]; ok {
+				handler(c)
+			} else if handler, ok = route.Handlers[""]; ok {
+				handler(c)
+			} else {
+				c.Header("Allow", strings.Join(route.Methods, ", "))
+				c.AbortWithStatus(http.StatusMethodNotAllowed)
+			}
 			return
 		}
 	}
+
+	if w.config.LogAltRouteMisses {
+		w.config.Logger.Info().
+			Str("path", path).
+			Str("method", c.Request.Method).
+			Int("altRoutes", len(rt.altRoutes)).
+			Uint64("requestID", RequestID(c)).
+			Msg("no route matched, alt routing attempted")
+	}
 }
 
 func (w *WebServer) httpLogger(logger *zerolog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var requestID uint64
+		// fast path for the requests known to be not logged before processing
+		if w.noLoggingPaths[c.Request.URL.Path] {
+			c.Set("httpNoLogging", true)
+		}
+		if _, exists := c.Get("httpNoLogging"); exists && len(w.afterResponse.hooks) == 0 {
+			c.Next()
+			return
+		}
 
 		start := time.Now()
 		path := c.Request.URL.Path
 		raw := c.Request.URL.RawQuery
+		requestID := RequestID(c)
+		writer := &responseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
 
-		if v, ok := c.Get("requestID"); ok {
-			if requestID, ok = v.(uint64); !ok {
-				requestID = 0
-			}
-		}
-
-		c.Get("requestID")
 		// Process request
 		c.Next()
 
+		latency := time.Now().Sub(start)
+		if route := c.FullPath(); route != "" {
+			w.latency.observe(route, latency)
+		}
+
+		if len(w.afterResponse.hooks) > 0 {
+			defer w.runAfterResponse(c, latency)
+		}
+
 		if raw != "" {
 			path = path + "?" + raw
 		}
@@ -150,17 +634,94 @@ func (w *WebServer) httpLogger(logger *zerolog.Logger) gin.HandlerFunc {
 			return
 		}
 
-		logger.Info().
-			Int64("latency", time.Now().Sub(start).Milliseconds()).
-			Str("clientIp", c.ClientIP()).
-			Str("path", path).
-			Str("method", c.Request.Method).
-			Int("statusCode", c.Writer.Status()).
-			Int("bodySize", c.Writer.Size()).
-			Uint64("requestID", requestID).
-			Msg("http request")
+		entry := accessLogEntry{
+			latency:    latency,
+			clientIP:   w.loggedClientIP(c),
+			path:       truncatePath(path, w.config.MaxLoggedPathLength),
+			route:      c.FullPath(),
+			method:     c.Request.Method,
+			statusCode: c.Writer.Status(),
+			bodySize:   c.Writer.Size(),
+			requestID:  requestID,
+			cookies:    w.loggedCookies(c),
+			trailers:   w.loggedTrailers(c),
+		}
 
+		if c.GetBool("timedOut") {
+			entry.timedOut = true
+		}
+		if c.Request.TLS != nil {
+			entry.serverName = c.Request.TLS.ServerName
+		}
+		if l := w.accessLogger(logger); l != logger {
+			entry.logger = l
+			if l.GetLevel() <= zerolog.DebugLevel {
+				entry.headers = w.requestHeaders(c)
+			}
+		}
+		if w.config.Compression {
+			entry.contentEncoding = "identity"
+			if encoding := c.GetString("contentEncoding"); encoding != "" {
+				entry.contentEncoding = encoding
+				if entry.bodySize > 0 {
+					entry.compressionRatio = float64(c.GetInt("uncompressedSize")) / float64(entry.bodySize)
+				}
+			}
+		}
+		if writer.writeErr != nil {
+			entry.statusCode = StatusClientClosedRequest
+			entry.clientAbort = true
+			entry.writeErr = writer.writeErr.Error()
+		}
+
+		if w.ndjsonLog != nil {
+			w.ndjsonLog.write(entry, logger)
+		} else if w.accessLog != nil {
+			w.accessLog.write(entry)
+		} else {
+			entry.write(logger)
+		}
+	}
+}
+
+// loggedCookies returns the allow-listed cookies present on the request
+func (w *WebServer) loggedCookies(c *gin.Context) (cookies []string) {
+	for _, name := range w.config.LogCookies {
+		cookie, err := c.Request.Cookie(name)
+		if err != nil {
+			continue
+		}
+		if w.config.LogCookieValues {
+			cookies = append(cookies, name+"="+cookie.Value)
+		} else {
+			cookies = append(cookies, name)
+		}
+	}
+	return
+}
+
+// requestHeader returns the request header value looking it up by the canonical name,
+// the non-canonical keys (set directly to the header map) are matched case-insensitively
+func requestHeader(c *gin.Context, name string) string {
+	if v := c.Request.Header.Get(name); v != "" {
+		return v
+	}
+	for key, values := range c.Request.Header {
+		if len(values) > 0 && strings.EqualFold(key, name) {
+			return values[0]
+		}
 	}
+	return ""
+}
+
+// RequestID returns the ID assigned to the request by the webserver, 0 if it isn't set
+func RequestID(c *gin.Context) uint64 {
+	if v, ok := c.Get("requestID"); ok {
+		if requestID, ok := v.(uint64); ok {
+			return requestID
+		}
+	}
+	return 0
 }
 
 func (w *WebServer) robotsDetect(names []string) gin.HandlerFunc {
@@ -170,7 +731,7 @@ func (w *WebServer) robotsDetect(names []string) gin.HandlerFunc {
 		regexps = append(regexps, regexp.MustCompile("(?i)"+name))
 	}
 	return func(c *gin.Context) {
-		if c.GetHeader("X-Robot") != "" {
+		if requestHeader(c, w.config.RobotHeader) != "" {
 			c.Set("robot", true)
 		} else {
 			c.Set("robot", false)
@@ -186,11 +747,11 @@ func (w *WebServer) robotsDetect(names []string) gin.HandlerFunc {
 
 // Run runs a gin server,
 // this method will block the calling goroutine indefinitely unless an error happens.
-func (w WebServer) Run() {
+func (w *WebServer) Run() {
 	log := *(w.config.Logger)
 	log.Info().Str("Addr", w.config.Addr).Int("Port", w.config.Port).Msg("Starting listener")
 
-	err := w.gin.Run(w.bindTo(w.config.Addr, w.config.Port))
+	err := http.ListenAndServe(w.bindTo(w.config.Addr, w.config.Port), w.Handler())
 
 	if err != nil {
 		log.Error().Msgf("webserver startup error: %v", err)
@@ -204,13 +765,16 @@ func (w *WebServer) RunBg() (err error) {
 	log.Info().Str("Addr", w.config.Addr).Int("Port", w.config.Port).Msg("Starting listener")
 
 	w.srv = &http.Server{
-		Addr:    w.bindTo(w.config.Addr, w.config.Port),
-		Handler: w.gin.Handler(),
+		Addr:      w.bindTo(w.config.Addr, w.config.Port),
+		Handler:   w.Handler(),
+		ConnState: w.connState,
 	}
 
-	startupError := make(chan error)
+	startupError := make(chan error, 1)
+	atomic.StoreInt32(&w.running, 1)
 	go func() {
-		e := w.srv.ListenAndServe()
+		e := w.listenAndServe()
+		atomic.StoreInt32(&w.running, 0)
 		if e != http.ErrServerClosed {
 			startupError <- e
 		}
@@ -230,15 +794,74 @@ func (w *WebServer) RunBg() (err error) {
 	return
 }
 
-// Shutdown performs gracefully shutdown of a server started with RunBg
+// Shutdown performs gracefully shutdown of a server started with RunBg in phases: stops accepting new connections,
+// waits for the in-flight requests, cancels the periodic tasks and awaits them along with the async after-response hooks.
+// The connections still open when the context is done are closed forcibly and the work still pending is logged,
+// the buffered access logs are written until the context is done. It's the preferred way to stop the server
 func (w *WebServer) Shutdown(ctx context.Context) (err error) {
 	if w.srv != nil {
-		err = w.srv.Shutdown(ctx)
+		if err = w.srv.Shutdown(ctx); err != nil {
+			w.config.Logger.Warn().Int("connections", w.ActiveConnections()).Msg("webserver connections are still open on shutdown, closing")
+			_ = w.srv.Close()
+		}
+		atomic.StoreInt32(&w.running, 0)
 		w.config.Logger.Info().Msg("webserver shutdown")
 	}
-	return nil
+	w.lifecycle.stop()
+	if !w.lifecycle.wait(ctx) {
+		w.config.Logger.Warn().Strs("pending", w.lifecycle.pendingWork()).Msg("webserver work is still pending on shutdown")
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	w.closeLogs(ctx)
+	return
+}
+
+// Close immediately closes the listener and all the connections of a server started with RunBg,
+// the in-flight requests are cut and the periodic tasks are canceled without waiting.
+// Use it when the graceful Shutdown isn't possible or its deadline is exceeded
+func (w *WebServer) Close() (err error) {
+	if w.srv != nil {
+		err = w.srv.Close()
+		atomic.StoreInt32(&w.running, 0)
+		w.config.Logger.Info().Msg("webserver closed")
+	}
+	w.lifecycle.stop()
+	//the buffered access logs are dropped instead of waiting for a stalled sink
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w.closeLogs(ctx)
+	return
+}
+
+// closeLogs flushes the buffered access logs until the context is done and closes the access log file
+func (w *WebServer) closeLogs(ctx context.Context) {
+	if w.accessLog != nil {
+		w.accessLog.flush(ctx)
+	}
+	if w.ndjsonLog != nil {
+		w.ndjsonLog.close(ctx)
+	}
+	if w.accessLogFile != nil {
+		_ = w.accessLogFile.Close()
+	}
+}
+
+// IsRunning reports whether a server started with RunBg is serving, it's false after Shutdown or Close
+func (w *WebServer) IsRunning() bool {
+	return atomic.LoadInt32(&w.running) == 1
+}
+
+// listenAndServe starts the listener (TLS one if configured) and serves it with the server srv
+func (w *WebServer) listenAndServe() error {
+	ln, err := net.Listen("tcp", w.srv.Addr)
+	if err != nil {
+		return err
+	}
+	return w.srv.Serve(w.tlsListener(w.deadlineListener(ln)))
 }
 
-func (w WebServer) bindTo(host string, port int) string {
+func (w *WebServer) bindTo(host string, port int) string {
 	return host + ":" + strconv.Itoa(port)
 }
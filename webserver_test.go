@@ -1,13 +1,19 @@
 package webserver
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -42,6 +48,81 @@ func (s PublicWebService) indexHandler(ctx *gin.Context) {
 	ctx.String(200, "HELLO")
 }
 
+// testWebService is a configurable service used to check the webserver features
+type testWebService struct {
+	routes      []WebRoute
+	altRoutes   []WebRoute
+	middlewares []func(ctx *gin.Context)
+	initErr     error
+}
+
+func (s *testWebService) Init(router *gin.Engine) error {
+	return s.initErr
+}
+
+func (s *testWebService) GinRoutes() []WebRoute {
+	return s.routes
+}
+
+func (s *testWebService) AltRoutes() []WebRoute {
+	return s.altRoutes
+}
+
+func (s *testWebService) Middlewares() []func(ctx *gin.Context) {
+	return s.middlewares
+}
+
+func newTestLogger(out io.Writer) *zerolog.Logger {
+	logger := zerolog.New(out).With().Timestamp().Logger()
+	return &logger
+}
+
+// newTestWebServer creates a webserver with the loggers not defined in config writing to out
+func newTestWebServer(t *testing.T, config WebServerConfig, out io.Writer) *WebServer {
+	if config.Logger == nil {
+		config.Logger = newTestLogger(out)
+	}
+	if config.LoggerHttp == nil {
+		config.LoggerHttp = config.Logger
+	}
+	webServer, err := NewWebServer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return webServer
+}
+
+// syncBuffer is a buffer safe to write from the timer goroutines
+type syncBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Reset() {
+	b.Lock()
+	defer b.Unlock()
+	b.buf.Reset()
+}
+
+func (b *syncBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.String()
+}
+
+// serve passes the request through the webserver handler without starting a listener
+func serve(w *WebServer, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	w.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
 func TestWebServer_Run(t *testing.T) {
 	logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.StampMicro}).With().Timestamp().Logger()
 	service := &PublicWebService{
@@ -129,4 +210,376 @@ func TestWebServer_RunBg(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error on shutdown: %v", err)
 	}
+	if webServer.IsRunning() {
+		t.Fatalf("Webserver is running after shutdown")
+	}
+}
+
+func TestWebServer_LogCookies(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{LogCookies: []string{"session", "lang"}}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "secret-session-value"})
+	req.AddCookie(&http.Cookie{Name: "tracking", Value: "tracking-value"})
+	serve(webServer, req)
+
+	var entry struct {
+		Cookies []string `json:"cookies"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Can't decode access log %q: %v", logs.String(), err)
+	}
+	if len(entry.Cookies) != 1 || entry.Cookies[0] != "session" {
+		t.Fatalf("Wrong logged cookies: %v", entry.Cookies)
+	}
+	if strings.Contains(logs.String(), "secret-session-value") || strings.Contains(logs.String(), "tracking") {
+		t.Fatalf("Access log leaks cookies: %v", logs.String())
+	}
+
+	logs.Reset()
+	webServer.config.LogCookieValues = true
+	serve(webServer, req)
+	if !strings.Contains(logs.String(), "session=secret-session-value") {
+		t.Fatalf("Cookie value isn't logged when permitted: %v", logs.String())
+	}
+}
+
+func TestWebServer_AltRoutesDecodedPath(t *testing.T) {
+	var logs bytes.Buffer
+
+	service := &testWebService{
+		altRoutes: []WebRoute{
+			{Path: `^/files/my file$`, Method: "GET", Handler: func(c *gin.Context) { c.String(200, "DECODED") }},
+			{Path: `^/raw/a%2Fb$`, Method: "GET", Handler: func(c *gin.Context) { c.String(200, "ENCODED") }},
+		},
+	}
+	tests := []struct {
+		decoded bool
+		path    string
+		status  int
+		body    string
+	}{
+		{false, "/files/my%20file", http.StatusNotFound, ""},
+		{true, "/files/my%20file", http.StatusOK, "DECODED"},
+		{false, "/raw/a%2Fb", http.StatusOK, "ENCODED"},
+		{true, "/raw/a%2Fb", http.StatusNotFound, ""},
+	}
+
+	for _, test := range tests {
+		webServer := newTestWebServer(t, WebServerConfig{AltRoutesDecodedPath: test.decoded}, &logs)
+		webServer.ServiceRegister("", service)
+
+		rec := serve(webServer, httptest.NewRequest("GET", test.path, nil))
+		if rec.Code != test.status || (test.body != "" && rec.Body.String() != test.body) {
+			t.Fatalf("Wrong answer for %v (decoded: %v): %v %v", test.path, test.decoded, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestWebServer_LogAltRouteMisses(t *testing.T) {
+	var logs, httpLogs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{
+		LoggerHttp:        newTestLogger(&httpLogs),
+		LogAltRouteMisses: true,
+	}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		altRoutes: []WebRoute{
+			{Path: `^/alt/\d+$`, Method: "GET", Handler: func(c *gin.Context) { c.String(200, "ALT") }},
+		},
+	})
+
+	serve(webServer, httptest.NewRequest("GET", "/alt/1", nil))
+	if logs.Len() != 0 {
+		t.Fatalf("Matched alt route is logged as a miss: %v", logs.String())
+	}
+
+	rec := serve(webServer, httptest.NewRequest("GET", "/alt/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Wrong status code: %v", rec.Code)
+	}
+
+	var entry struct {
+		Path      string `json:"path"`
+		AltRoutes int    `json:"altRoutes"`
+		RequestID uint64 `json:"requestID"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Can't decode alt route miss log %q: %v", logs.String(), err)
+	}
+	if entry.Path != "/alt/unknown" || entry.AltRoutes != 1 || entry.RequestID != 2 {
+		t.Fatalf("Wrong alt route miss log: %v", logs.String())
+	}
+}
+
+func TestWebServer_RobotHeaderCasing(t *testing.T) {
+	var logs bytes.Buffer
+	var robot bool
+
+	webServer := newTestWebServer(t, WebServerConfig{RobotHeader: "x-robot"}, &logs)
+	if webServer.config.RobotHeader != "X-Robot" {
+		t.Fatalf("Robot header name isn't canonicalized: %v", webServer.config.RobotHeader)
+	}
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/", Method: "GET", Handler: func(c *gin.Context) { robot = c.GetBool("robot") }},
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("x-robot", "1")
+	if serve(webServer, req); !robot {
+		t.Fatalf("Lowercase X-robot header isn't honored")
+	}
+
+	//non-canonical key set directly to the header map
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header["x-robot"] = []string{"1"}
+	if serve(webServer, req); !robot {
+		t.Fatalf("Non-canonical x-robot header key isn't honored")
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	if serve(webServer, req); robot {
+		t.Fatalf("Request without X-Robot header is detected as robot")
+	}
+}
+
+func TestWebServer_Close(t *testing.T) {
+	var logs bytes.Buffer
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	webServer := newTestWebServer(t, WebServerConfig{Port: 9097}, &logs)
+	webServer.ServiceRegister("", &testWebService{
+		routes: []WebRoute{
+			{Path: "/slow", Method: "GET", Handler: func(c *gin.Context) {
+				close(entered)
+				<-release
+				c.String(200, "DONE")
+			}},
+		},
+	})
+
+	if webServer.IsRunning() {
+		t.Fatalf("Webserver is running before start")
+	}
+	if err := webServer.RunBg(); err != nil {
+		t.Fatal(err)
+	}
+	if !webServer.IsRunning() {
+		t.Fatalf("Webserver isn't running after start")
+	}
+
+	requestErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://localhost:9097/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		requestErr <- err
+	}()
+
+	<-entered
+	if err := webServer.Close(); err != nil {
+		t.Fatalf("Error on close: %v", err)
+	}
+	if webServer.IsRunning() {
+		t.Fatalf("Webserver is running after close")
+	}
+
+	select {
+	case err := <-requestErr:
+		if err == nil {
+			t.Fatalf("In-flight request wasn't cut by close")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("In-flight request wasn't cut by close in time")
+	}
+}
+
+func TestWebServer_CaseInsensitiveRoutes(t *testing.T) {
+	var logs bytes.Buffer
+	service := &testWebService{
+		routes: []WebRoute{
+			{Path: "/users", Method: "GET", Handler: func(c *gin.Context) { c.String(200, "USERS") }},
+			{Path: "/users/:name", Method: "GET", Handler: func(c *gin.Context) { c.String(200, c.Param("name")) }},
+		},
+		altRoutes: []WebRoute{
+			{Path: `(?i)^/items/\d+$`, Method: "GET", Handler: func(c *gin.Context) { c.String(200, "ITEM") }},
+		},
+	}
+
+	webServer := newTestWebServer(t, WebServerConfig{CaseInsensitiveRoutes: true}, &logs)
+	webServer.ServiceRegister("", service)
+
+	if rec := serve(webServer, httptest.NewRequest("GET", "/Users", nil)); rec.Body.String() != "USERS" {
+		t.Fatalf("Mixed-case path doesn't reach the route: %v %v", rec.Code, rec.Body.String())
+	}
+	//the path parameters are lowercased with the path
+	if rec := serve(webServer, httptest.NewRequest("GET", "/Users/Alice", nil)); rec.Body.String() != "alice" {
+		t.Fatalf("Wrong path parameter: %v %v", rec.Code, rec.Body.String())
+	}
+	if rec := serve(webServer, httptest.NewRequest("GET", "/ITEMS/1", nil)); rec.Body.String() != "ITEM" {
+		t.Fatalf("Mixed-case path doesn't reach the alt route: %v %v", rec.Code, rec.Body.String())
+	}
+
+	webServer = newTestWebServer(t, WebServerConfig{CaseInsensitiveRoutes: true, CaseInsensitiveRedirect: true}, &logs)
+	webServer.ServiceRegister("", service)
+
+	rec := serve(webServer, httptest.NewRequest("GET", "/Users?page=2", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/users?page=2" {
+		t.Fatalf("Wrong redirect to the lowercase path: %v %v", rec.Code, rec.Header().Get("Location"))
+	}
+	rec = serve(webServer, httptest.NewRequest("POST", "/Users", nil))
+	if rec.Code != http.StatusPermanentRedirect || rec.Header().Get("Location") != "/users" {
+		t.Fatalf("Wrong redirect of POST to the lowercase path: %v %v", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestWebServer_ServiceInitError(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+	err := webServer.ServiceRegister("/api", &testWebService{
+		routes:  []WebRoute{{Path: "/degraded", Method: "GET", Handler: func(c *gin.Context) { c.Status(http.StatusOK) }}},
+		initErr: errors.New("no database"),
+	})
+	if err != nil {
+		t.Fatalf("Init error fails the registration: %v", err)
+	}
+	if !strings.Contains(logs.String(), "no database") {
+		t.Fatalf("Init error isn't logged: %v", logs.String())
+	}
+	if rec := serve(webServer, httptest.NewRequest("GET", "/api/degraded", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("Route isn't mounted after the init error: %v", rec.Code)
+	}
+}
+
+// lazyWebService builds its routes in Init
+type lazyWebService struct {
+	testWebService
+}
+
+func (s *lazyWebService) Init(router *gin.Engine) error {
+	s.routes = []WebRoute{{Path: "/lazy", Method: "GET", Handler: func(c *gin.Context) { c.Status(http.StatusOK) }}}
+	return nil
+}
+
+func TestWebServer_ServiceRoutesBuiltInInit(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{EmptyServices: EmptyServiceError}, &bytes.Buffer{})
+	if err := webServer.ServiceRegister("", &lazyWebService{}); err != nil {
+		t.Fatalf("Service building its routes in Init is treated as empty: %v", err)
+	}
+	if rec := serve(webServer, httptest.NewRequest("GET", "/lazy", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("Route built in Init isn't mounted: %v", rec.Code)
+	}
+}
+
+func TestWebServer_ServiceRegisterIf(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+	webServer.ServiceRegisterIf(true, "/enabled", &PublicWebService{})
+	webServer.ServiceRegisterIf(false, "/disabled", &PublicWebService{})
+
+	if rec := serve(webServer, httptest.NewRequest("GET", "/enabled/", nil)); rec.Body.String() != "HELLO" {
+		t.Fatalf("Enabled service isn't registered: %v %v", rec.Code, rec.Body.String())
+	}
+	if rec := serve(webServer, httptest.NewRequest("GET", "/disabled/", nil)); rec.Code != http.StatusNotFound {
+		t.Fatalf("Disabled service is registered: %v", rec.Code)
+	}
+}
+
+func TestWebServer_EmptyService(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+	if err := webServer.ServiceRegister("/empty", &testWebService{}); err != nil {
+		t.Fatalf("Empty service registration fails with the warn policy: %v", err)
+	}
+	if !strings.Contains(logs.String(), "has no routes and no middlewares") || !strings.Contains(logs.String(), "testWebService") {
+		t.Fatalf("Empty service isn't warned: %v", logs.String())
+	}
+
+	logs.Reset()
+	webServer = newTestWebServer(t, WebServerConfig{EmptyServices: EmptyServiceIgnore}, &logs)
+	if err := webServer.ServiceRegister("/empty", &testWebService{}); err != nil || logs.Len() != 0 {
+		t.Fatalf("Empty service isn't ignored: %v %v", err, logs.String())
+	}
+
+	webServer = newTestWebServer(t, WebServerConfig{EmptyServices: EmptyServiceError}, &logs)
+	err := webServer.ServiceRegister("/empty", &PublicWebService{}, &testWebService{})
+	if !errors.Is(err, ErrEmptyService) {
+		t.Fatalf("Empty service registration doesn't fail with the error policy: %v", err)
+	}
+	if len(webServer.gin.Routes()) != 0 {
+		t.Fatalf("Services are partially registered: %v", webServer.gin.Routes())
+	}
+}
+
+func TestWebServer_MaxAltRoutesPerService(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{MaxAltRoutesPerService: 2}, &bytes.Buffer{})
+
+	handler := func(c *gin.Context) { c.Status(http.StatusOK) }
+	if err := webServer.ServiceRegister("", &testWebService{altRoutes: []WebRoute{
+		{Path: "^/a", Handler: handler},
+		{Path: "^/b", Handler: handler},
+	}}); err != nil {
+		t.Fatalf("Service within the cap isn't registered: %v", err)
+	}
+
+	err := webServer.ServiceRegister("", &testWebService{altRoutes: []WebRoute{
+		{Path: "^/c", Handler: handler},
+		{Path: "^/d", Handler: handler},
+		{Path: "^/e", Handler: handler},
+	}})
+	if !errors.Is(err, ErrTooManyAltRoutes) {
+		t.Fatalf("Service exceeding the cap is registered: %v", err)
+	}
+	if len(webServer.altRoutes) != 2 {
+		t.Fatalf("Wrong number of alt routes: %v", len(webServer.altRoutes))
+	}
+	if w := serve(webServer, httptest.NewRequest("GET", "/c", nil)); w.Code != http.StatusNotFound {
+		t.Fatalf("Alt route of the rejected service is served: %v", w.Code)
+	}
+}
+
+func TestWebServer_AltRouteMethods(t *testing.T) {
+	webServer := newTestWebServer(t, WebServerConfig{}, &bytes.Buffer{})
+	webServer.ServiceRegister("", &testWebService{
+		altRoutes: []WebRoute{
+			{Path: `^/items/\d+$`, Method: "GET", Handler: func(c *gin.Context) { c.String(200, "GET") }},
+			{Path: `^/items/\d+$`, Method: "post", Handler: func(c *gin.Context) { c.String(200, "POST") }},
+			{Path: `^/any$`, Handler: func(c *gin.Context) { c.String(200, "ANY") }},
+		},
+	})
+
+	if len(webServer.altRoutes) != 2 {
+		t.Fatalf("Routes of the same pattern aren't grouped: %v", len(webServer.altRoutes))
+	}
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{"GET", "/items/1", http.StatusOK, "GET"},
+		{"POST", "/items/1", http.StatusOK, "POST"},
+		{"DELETE", "/items/1", http.StatusMethodNotAllowed, ""},
+		{"DELETE", "/any", http.StatusOK, "ANY"},
+	}
+	for _, test := range tests {
+		rec := serve(webServer, httptest.NewRequest(test.method, test.path, nil))
+		if rec.Code != test.code || rec.Body.String() != test.body {
+			t.Fatalf("%v %v: wrong response %v %q", test.method, test.path, rec.Code, rec.Body.String())
+		}
+		if rec.Code == http.StatusMethodNotAllowed && rec.Header().Get("Allow") != "GET, POST" {
+			t.Fatalf("Wrong Allow header: %q", rec.Header().Get("Allow"))
+		}
+	}
 }
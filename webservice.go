@@ -4,12 +4,25 @@ import (
 	"github.com/gin-gonic/gin"
 	"regexp"
 	"strconv"
+	"time"
 )
 
 type WebRoute struct {
 	Path    string
 	Method  string
 	Handler func(ctx *gin.Context)
+	// ResponseContentType is set to the response before the handler is called, the handler may override it
+	ResponseContentType string
+	// Isolated runs the handler in a separate goroutine recovering its panics (500)
+	// and limiting its duration by IsolationTimeout (504), see isolatedHandler for the tradeoffs
+	Isolated         bool
+	IsolationTimeout time.Duration
+	// RateLimit limits the route requests rate per client IP in addition to the global limit
+	RateLimit *RateLimit
+	// Deprecated adds the Deprecation and Warning headers (and Sunset if set) to the route responses,
+	// the route usage is logged at most once a minute
+	Deprecated bool
+	Sunset     time.Time
 }
 
 type WebService interface {
@@ -0,0 +1,31 @@
+package webserver
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// StatusClientClosedRequest is the status code logged for the requests aborted by the client
+// in the middle of the response writing (nginx convention)
+const StatusClientClosedRequest = 499
+
+// responseWriter wraps the gin response writer to record the response writing errors
+type responseWriter struct {
+	gin.ResponseWriter
+	writeErr error
+}
+
+func (rw *responseWriter) Write(data []byte) (n int, err error) {
+	n, err = rw.ResponseWriter.Write(data)
+	if err != nil && rw.writeErr == nil {
+		rw.writeErr = err
+	}
+	return
+}
+
+func (rw *responseWriter) WriteString(s string) (n int, err error) {
+	n, err = rw.ResponseWriter.WriteString(s)
+	if err != nil && rw.writeErr == nil {
+		rw.writeErr = err
+	}
+	return
+}
@@ -0,0 +1,44 @@
+package webserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+)
+
+// brokenPipeWriter simulates the client disconnected before the response was written
+type brokenPipeWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (b brokenPipeWriter) Write(data []byte) (int, error) {
+	return 0, syscall.EPIPE
+}
+
+func TestWebServer_ClientAbort(t *testing.T) {
+	var logs bytes.Buffer
+
+	webServer := newTestWebServer(t, WebServerConfig{}, &logs)
+	webServer.ServiceRegister("", &PublicWebService{})
+
+	webServer.Handler().ServeHTTP(brokenPipeWriter{httptest.NewRecorder()}, httptest.NewRequest("GET", "/", nil))
+
+	var entry struct {
+		Level       string `json:"level"`
+		StatusCode  int    `json:"statusCode"`
+		ClientAbort bool   `json:"clientAbort"`
+		WriteError  string `json:"writeError"`
+		RequestID   uint64 `json:"requestID"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Can't decode access log %q: %v", logs.String(), err)
+	}
+	if !entry.ClientAbort || entry.StatusCode != StatusClientClosedRequest || entry.Level != "warn" {
+		t.Fatalf("Client abort isn't logged: %v", logs.String())
+	}
+	if entry.WriteError == "" || entry.RequestID != 1 {
+		t.Fatalf("Wrong client abort details: %v", logs.String())
+	}
+}